@@ -39,6 +39,15 @@ func main() {
 		return
 	}
 
+	configHash, err := config.HashFile(configFlag.ConfigFile)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load configuration file")
+		return
+	}
+
+	configStore := config.NewStore(configLoaded, configHash)
+	configWatcher := config.NewWatcher(viperInstance, validate, configStore)
+
 	logger.Info().Str("config_file", configFlag.ConfigFile).Msg("configuration file was loaded successfully")
-	app.Run(configLoaded, configFlag, logger)
+	app.Run(configStore, configWatcher, configFlag, logger)
 }
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -24,25 +28,131 @@ type Database struct {
 	Dbname   string        `mapstructure:"dbname" validate:"required"`
 	Sslmode  bool          `mapstructure:"sslmode"`
 	Timeout  time.Duration `mapstructure:"timeout" validate:"required"`
+
+	MaxOpenConns    int           `mapstructure:"max_open_conns" validate:"gte=0"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" validate:"gte=0"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" validate:"gte=0"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" validate:"gte=0"`
+
+	MigrationsDir string `mapstructure:"migrations_dir" validate:"omitempty,dir"`
+}
+
+type Param struct {
+	Name     string   `mapstructure:"name" validate:"required"`
+	Type     string   `mapstructure:"type" validate:"required,oneof=string int float bool date datetime uuid json"`
+	Required bool     `mapstructure:"required"`
+	Default  any      `mapstructure:"default"`
+	Regex    string   `mapstructure:"regex" validate:"omitempty,regexp"`
+	Enum     []string `mapstructure:"enum"`
+
+	// Min and Max bound the length of a string and the value of a number, the other types have no size to bound
+	Min *float64 `mapstructure:"min" validate:"excluded_if=Type bool,excluded_if=Type date,excluded_if=Type datetime,excluded_if=Type uuid,excluded_if=Type json"`
+	Max *float64 `mapstructure:"max" validate:"excluded_if=Type bool,excluded_if=Type date,excluded_if=Type datetime,excluded_if=Type uuid,excluded_if=Type json"`
+
+	// List params take a json array of values of Type, expanded by IN ({{param}}) clauses
+	List       bool `mapstructure:"list"`
+	MaxItems   int  `mapstructure:"max_items" validate:"gte=0"`
+	AllowEmpty bool `mapstructure:"allow_empty"`
+
+	// Direction of a procedure param: in (default), out or inout. Out params are not read from the request.
+	Direction string `mapstructure:"direction" validate:"omitempty,oneof=in out inout"`
+}
+
+// Pagination lets a select target return its rows by pages, either numbered pages or, with CursorColumn,
+// keyset pages ordered by this column
+type Pagination struct {
+	DefaultSize  int    `mapstructure:"default_size" validate:"required,gte=1"`
+	MaxSize      int    `mapstructure:"max_size" validate:"required,gtefield=DefaultSize"`
+	CursorColumn string `mapstructure:"cursor_column"`
+	CursorOrder  string `mapstructure:"cursor_order" validate:"omitempty,oneof=asc desc"`
+}
+
+// Step is a statement of a target running several statements in a single transaction
+type Step struct {
+	Name     string `mapstructure:"name" validate:"required,max=25"`
+	SqlQuery string `mapstructure:"sql" validate:"required"`
+	Mode     string `mapstructure:"mode" validate:"omitempty,oneof=query exec"`
+}
+
+// Shape nests the rows of a target into json documents grouped by the Key columns. A column named like
+// lines[].product fills the lines array of a document, a column named like teacher.name its teacher object.
+type Shape struct {
+	Key []string `mapstructure:"key" validate:"required,min=1"`
+}
+
+// ColumnType overrides the json type of the values of a column : string, number, time (RFC3339), date,
+// uuid, base64, or raw to keep the value returned by the driver
+type ColumnType struct {
+	Column string `mapstructure:"column" validate:"required"`
+	Type   string `mapstructure:"type" validate:"required,oneof=string number time date uuid base64 raw"`
+}
+
+// CSV describes the csv files of a batch target. Without header, the columns of the file are the batch fields in
+// their order.
+type CSV struct {
+	Delimiter string `mapstructure:"delimiter" validate:"omitempty,len=1"`
+	Quote     string `mapstructure:"quote" validate:"omitempty,len=1"`
+	Escape    string `mapstructure:"escape" validate:"omitempty,len=1"`
+	Encoding  string `mapstructure:"encoding" validate:"omitempty,oneof=utf-8 latin-1 utf-16"`
+	HasHeader bool   `mapstructure:"has_header"`
+	// Columns maps header names to batch fields, a batch field without column is read from the column named
+	// after it
+	Columns []CSVColumn `mapstructure:"columns" validate:"excluded_if=HasHeader false,dive"`
+}
+
+type CSVColumn struct {
+	Header string `mapstructure:"header" validate:"required"`
+	Field  string `mapstructure:"field" validate:"required"`
 }
 
 type Target struct {
-	Name           string `mapstructure:"name" validate:"required,max=25"`
-	DataSourceName string `mapstructure:"data_source_name" validate:"required"`
-	Multi          bool   `mapstructure:"multi"`
-	BatchSize      int    `mapstructure:"batch_size" validate:"required_if=Multi true"`
-	BufferSize     int    `mapstructure:"buffer_size" validate:"required_if=Multi true"`
-	BatchFields    string `mapstructure:"batch_fields" validate:"required_if=Multi true"`
-	SqlQuery       string `mapstructure:"sql" validate:"required"`
+	Name           string      `mapstructure:"name" validate:"required,max=25"`
+	DataSourceName string      `mapstructure:"data_source_name" validate:"required"`
+	Multi          bool        `mapstructure:"multi"`
+	BatchSize      int         `mapstructure:"batch_size" validate:"required_if=Multi true"`
+	BufferSize     int         `mapstructure:"buffer_size" validate:"required_if=Multi true"`
+	BatchFields    string      `mapstructure:"batch_fields" validate:"required_if=Multi true"`
+	CSV            *CSV        `mapstructure:"csv" validate:"omitempty,excluded_if=Multi false"`
+	SqlQuery       string      `mapstructure:"sql" validate:"required_without_all=Steps Kind,excluded_with=Steps Kind"`
+	Mode           string      `mapstructure:"mode" validate:"omitempty,oneof=query exec"`
+	Params         []Param     `mapstructure:"params" validate:"dive"`
+	DefaultFormat  string      `mapstructure:"default_format" validate:"omitempty,oneof=json ndjson csv xlsx xml,excluded_with=Pagination Shape Steps Kind"`
+	Pagination     *Pagination `mapstructure:"pagination"`
+	Filterable     []string    `mapstructure:"filterable"`
+	Sortable       []string    `mapstructure:"sortable"`
+	Steps          []Step      `mapstructure:"steps" validate:"excluded_if=Multi true,excluded_with=Kind,dive"`
+
+	// BisectOnFailure runs a failed batch again in halves, down to single lines, to reject only the failing lines
+	BisectOnFailure bool `mapstructure:"bisect_on_failure" validate:"excluded_if=Multi false"`
+
+	// Kind procedure or function calls Procedure with the params, in their declaration order, instead of running sql
+	Kind      string `mapstructure:"kind" validate:"omitempty,oneof=procedure function,excluded_if=Multi true"`
+	Procedure string `mapstructure:"procedure" validate:"required_with=Kind,excluded_without=Kind"`
+
+	Shape *Shape `mapstructure:"shape" validate:"omitempty,excluded_with=Pagination Steps Kind"`
+
+	ColumnTypes []ColumnType `mapstructure:"column_types" validate:"dive"`
+}
+
+// Batches limits the batches running at the same time, by target or by datasource. A running batch whose
+// heartbeat is older than StaleAfter, as a batch of a stopped process, is marked failed. The failed lines of a
+// batch are retried at most MaxRetries times.
+type Batches struct {
+	LockScope            string        `mapstructure:"lock_scope" validate:"oneof=target datasource"`
+	MaxConcurrentBatches int           `mapstructure:"max_concurrent_batches" validate:"gte=1"`
+	StaleAfter           time.Duration `mapstructure:"stale_after" validate:"gte=1m"`
+	MaxRetries           int           `mapstructure:"max_retries" validate:"gte=1"`
 }
 
 type Config struct {
 	ApiGatewaySQL struct {
 		EnableSwagger bool   `mapstructure:"enable_swagger"`
 		Sqlitedb      string `mapstructure:"sqlitedb" validate:"required"`
+		StagingDir    string `mapstructure:"staging_dir" validate:"required"`
 		Auth          `mapstructure:"auth"`
 		Databases     []Database `mapstructure:"databases" validate:"gt=0,required,dive"`
 		Targets       []Target   `mapstructure:"targets" validate:"gt=0,required,dive"`
+		Batches       Batches    `mapstructure:"batches"`
 	} `mapstructure:"api_gateway_sql"`
 }
 
@@ -50,9 +160,14 @@ type Config struct {
 func setConfigDefaults(v *viper.Viper) {
 	v.SetDefault("api_gateway_sql.enable_swagger", true)
 	v.SetDefault("api_gateway_sql.sqlitedb", "/data/api_gateway_sql")
+	v.SetDefault("api_gateway_sql.staging_dir", "/data/staging")
 	v.SetDefault("api_gateway_sql.auth.enabled", false)
 	v.SetDefault("api_gateway_sql.auth.username", "")
 	v.SetDefault("api_gateway_sql.auth.password", "")
+	v.SetDefault("api_gateway_sql.batches.lock_scope", "target")
+	v.SetDefault("api_gateway_sql.batches.max_concurrent_batches", 1)
+	v.SetDefault("api_gateway_sql.batches.stale_after", "10m")
+	v.SetDefault("api_gateway_sql.batches.max_retries", 3)
 	v.SetDefault("api_gateway_sql.databases", make([]Database, 0))
 	v.SetDefault("api_gateway_sql.targets", make([]Target, 0))
 }
@@ -81,12 +196,22 @@ func LoadConfig(viperInstance *viper.Viper, validate *validator.Validate) (*Conf
 
 	setConfigDefaults(viperInstance)
 
+	if err := validate.RegisterValidation("regexp", isRegexp); err != nil {
+		return nil, fmt.Errorf("unable to register regexp validation: %w", err)
+	}
+	validate.RegisterStructValidation(validateTargetParams, Target{})
+
 	// Parse configuration file to Config struct
 	var config Config
 	if err := viperInstance.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal config struct: %w", err)
 	}
 
+	// Resolve environment variable and secret file references of the credentials
+	if err := interpolateConfig(&config); err != nil {
+		return nil, err
+	}
+
 	// Validate config struct
 	if err := validate.Struct(config); err != nil {
 		if _, ok := err.(*validator.InvalidValidationError); ok {
@@ -101,6 +226,59 @@ func LoadConfig(viperInstance *viper.Viper, validate *validator.Validate) (*Conf
 	return &config, nil
 }
 
+// isRegexp validates the fields tagged regexp, which must hold a regular expression compiling with the regexp package
+func isRegexp(field validator.FieldLevel) bool {
+	_, err := regexp.Compile(field.Field().String())
+	return err == nil
+}
+
+// validateTargetParams checks that each {{param}} of the sql and steps of a target is declared, when the target
+// declares its params or is a batch target : a param, a batch field, or a column of a previous step. The params
+// bound by the gateway to the cursor and to the filters are not declared.
+func validateTargetParams(structLevel validator.StructLevel) {
+	target := structLevel.Current().Interface().(Target)
+
+	declared := make(map[string]bool)
+	switch {
+	case target.Multi:
+		for _, field := range strings.Split(target.BatchFields, ";") {
+			declared[field] = true
+		}
+	case len(target.Params) > 0:
+		for _, param := range target.Params {
+			declared[param.Name] = true
+		}
+	default:
+		return
+	}
+
+	if !paramsDeclared(target.SqlQuery, declared, nil) {
+		structLevel.ReportError(target.SqlQuery, "SqlQuery", "sql", "declared_params", "")
+	}
+
+	previousSteps := make(map[string]bool, len(target.Steps))
+	for idx, step := range target.Steps {
+		if !paramsDeclared(step.SqlQuery, declared, previousSteps) {
+			structLevel.ReportError(step.SqlQuery, fmt.Sprintf("Steps[%d].SqlQuery", idx), "sql", "declared_params", "")
+		}
+		previousSteps[step.Name] = true
+	}
+}
+
+func paramsDeclared(sqlQuery string, declared map[string]bool, previousSteps map[string]bool) bool {
+	for _, name := range sqlqueryhelper.ParamNames(sqlQuery) {
+		stepName, _, isStepResult := strings.Cut(name, ".")
+		switch {
+		case declared[name], isStepResult && previousSteps[stepName]:
+		case name == sqlqueryhelper.CursorParam, strings.HasPrefix(name, sqlqueryhelper.FilterParamPrefix):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
 // GetTargetByName is a method of Config struct for retreive target by his name
 func (config *Config) GetTargetByName(targetName string) (Target, bool) {
 	var target Target
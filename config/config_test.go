@@ -481,6 +481,619 @@ api_gateway_sql:
     data_source_name: "xxxxx"
     sql: "select * from student"
     Multi: true
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "mariadb"
+    host: "127.0.0.1"
+    port: 3306
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student"
+    pagination:
+      default_size: 50
+      max_size: 20
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "mariadb"
+    host: "127.0.0.1"
+    port: 3306
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student"
+    default_format: csv
+    pagination:
+      default_size: 20
+      max_size: 50
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "mariadb"
+    host: "127.0.0.1"
+    port: 3306
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student"
+    steps:
+    - name: "header"
+      sql: "insert into invoice (total) values (0)"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "mariadb"
+    host: "127.0.0.1"
+    port: 3306
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    default_format: csv
+    steps:
+    - name: "header"
+      sql: "select * from invoice"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "mariadb"
+    host: "127.0.0.1"
+    port: 3306
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    steps:
+    - name: "header"
+      sql: ""
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "mariadb"
+    host: "127.0.0.1"
+    port: 3306
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    multi: true
+    batch_size: 10
+    buffer_size: 10
+    batch_fields: "id"
+    steps:
+    - name: "header"
+      sql: "insert into invoice (total) values ({{id}})"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    kind: procedure
+    procedure: "transfer"
+    sql: "call transfer({{id}})"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    kind: procedure
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    kind: function
+    procedure: "list_accounts"
+    default_format: csv
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    kind: procedure
+    procedure: "transfer"
+    params:
+    - name: "balance"
+      type: "float"
+      direction: "output"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    kind: trigger
+    procedure: "transfer"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student where name = {{name}}"
+    params:
+    - name: "name"
+      type: "string"
+      regex: "^[a-z"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student where born_on >= {{born}}"
+    params:
+    - name: "born"
+      type: "date"
+      min: 1
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student where born_on >= {{born}}"
+    params:
+    - name: "born"
+      type: "bool"
+      max: 1
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select * from student where name = {{name}} and class_id = {{class}}"
+    params:
+    - name: "name"
+      type: "string"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    steps:
+    - name: "line"
+      sql: "insert into invoice_line (invoice_id, product) values ({{header.id}}, {{product}})"
+    - name: "header"
+      sql: "insert into invoice (customer) values ({{customer}}) returning id"
+    params:
+    - name: "customer"
+      type: "int"
+    - name: "product"
+      type: "string"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select s.id, c.name as \"classes[].name\" from school s left join class c on c.school_id = s.id"
+    shape:
+      key: []
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select s.id, c.name as \"classes[].name\" from school s left join class c on c.school_id = s.id"
+    shape:
+      key: [id]
+    pagination:
+      default_size: 20
+      max_size: 100
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select s.id, c.name as \"classes[].name\" from school s left join class c on c.school_id = s.id"
+    default_format: csv
+    shape:
+      key: [id]
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select price from product"
+    column_types:
+    - column: "price"
+      type: "decimal"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    multi: true
+    batch_size: 10
+    buffer_size: 50
+    batch_fields: "name;address"
+    sql: "insert into school (name, address) values ({{name}}, {{address}})"
+    csv:
+      delimiter: "||"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    multi: true
+    batch_size: 10
+    buffer_size: 50
+    batch_fields: "name;address"
+    sql: "insert into school (name, address) values ({{name}}, {{address}})"
+    csv:
+      columns:
+      - header: "school name"
+        field: "name"
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "insert into school (name, address) values ({{name}}, {{address}})"
+    bisect_on_failure: true
+`),
+		[]byte(`---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  batches:
+    lock_scope: "database"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "xxxxx"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "xxxxx"
+    password: "xxxxx"
+    dbname: "xxxxx"
+    sslmode: false
+    timeout: "10s"
+  targets:
+  - name: "xxxxx"
+    data_source_name: "xxxxx"
+    sql: "select 1"
 `),
 	}
 
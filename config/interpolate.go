@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const secretFilePrefix string = "file:"
+
+// envReferenceRegex matches ${VAR}, ${VAR:-default} and the $${ escape sequence
+var envReferenceRegex = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// configField is a string field of the configuration, with its mapstructure path used to name the field that failed
+type configField struct {
+	path  string
+	value *string
+}
+
+// interpolateConfig resolves environment variable and secret file references in the credentials of the
+// configuration : the auth username and password, and the host, username, password and dbname of the databases.
+// The other fields, such as the sql of the targets, are kept as written.
+func interpolateConfig(config *Config) error {
+	fields := []configField{
+		{"api_gateway_sql.auth.username", &config.ApiGatewaySQL.Auth.Username},
+		{"api_gateway_sql.auth.password", &config.ApiGatewaySQL.Auth.Password},
+	}
+
+	for idx := range config.ApiGatewaySQL.Databases {
+		database := &config.ApiGatewaySQL.Databases[idx]
+		path := fmt.Sprintf("api_gateway_sql.databases[%d]", idx)
+		fields = append(fields,
+			configField{path + ".host", &database.Host},
+			configField{path + ".username", &database.Username},
+			configField{path + ".password", &database.Password},
+			configField{path + ".dbname", &database.Dbname},
+		)
+	}
+
+	for _, field := range fields {
+		resolved, err := resolveConfigValue(*field.value)
+		if err != nil {
+			return fmt.Errorf("unable to resolve %s: %w", field.path, err)
+		}
+		*field.value = resolved
+	}
+
+	return nil
+}
+
+// resolveConfigValue replaces a file:/path value by the file content and ${VAR} references by their environment value
+func resolveConfigValue(raw string) (string, error) {
+	if secretFile, found := strings.CutPrefix(raw, secretFilePrefix); found {
+		content, err := os.ReadFile(secretFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	var resolveErr error
+	resolved := envReferenceRegex.ReplaceAllStringFunc(raw, func(reference string) string {
+		if reference == "$${" {
+			return "${"
+		}
+
+		submatches := envReferenceRegex.FindStringSubmatch(reference)
+		if value, exist := os.LookupEnv(submatches[1]); exist && (value != "" || submatches[2] == "") {
+			return value
+		}
+		if submatches[2] != "" {
+			return submatches[3]
+		}
+
+		if resolveErr == nil {
+			resolveErr = fmt.Errorf("environment variable %s is not set", submatches[1])
+		}
+		return reference
+	})
+
+	return resolved, resolveErr
+}
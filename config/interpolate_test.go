@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+const interpolatedConfig string = `---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "${GATEWAY_USERNAME:-admin}"
+    password: "%s"
+  databases:
+  - name: "school"
+    type: "postgres"
+    host: "127.0.0.1"
+    port: 5432
+    username: "${DB_USERNAME}"
+    password: "%s"
+    dbname: "school"
+    timeout: "10s"
+  targets:
+  - name: "list-student"
+    data_source_name: "school"
+    sql: "select '${LITERAL}', 'file:/etc/passwd' from student where name = {{name}}"
+`
+
+func loadInterpolatedConfig(t *testing.T, authPassword, dbPassword string) (*config.Config, error) {
+	t.Helper()
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	content := strings.Replace(strings.Replace(interpolatedConfig, "%s", authPassword, 1), "%s", dbPassword, 1)
+	if err := v.ReadConfig(bytes.NewBufferString(content)); err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+
+	return config.LoadConfig(v, validator.New(validator.WithRequiredStructEnabled()))
+}
+
+func TestLoadConfig_ResolveEnvironmentAndSecretFile(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(secretFile, []byte("s3cr3t-from-file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	t.Setenv("DB_USERNAME", "school_user")
+	t.Setenv("GATEWAY_PASSWORD", "gateway-password")
+
+	cfg, err := loadInterpolatedConfig(t, "${GATEWAY_PASSWORD}", "file:"+secretFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ApiGatewaySQL.Username != "admin" {
+		t.Errorf("default value not applied, got %q", cfg.ApiGatewaySQL.Username)
+	}
+	if cfg.ApiGatewaySQL.Password != "gateway-password" {
+		t.Errorf("environment variable not resolved, got %q", cfg.ApiGatewaySQL.Password)
+	}
+
+	database := cfg.ApiGatewaySQL.Databases[0]
+	if database.Username != "school_user" {
+		t.Errorf("environment variable not resolved, got %q", database.Username)
+	}
+	if database.Password != "s3cr3t-from-file" {
+		t.Errorf("secret file not resolved, got %q", database.Password)
+	}
+
+	if sql := cfg.ApiGatewaySQL.Targets[0].SqlQuery; sql != "select '${LITERAL}', 'file:/etc/passwd' from student where name = {{name}}" {
+		t.Errorf("sql of a target interpolated, got %q", sql)
+	}
+}
+
+func TestLoadConfig_KeepEscapedReference(t *testing.T) {
+	t.Setenv("DB_USERNAME", "school_user")
+
+	cfg, err := loadInterpolatedConfig(t, "pass$${word}", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ApiGatewaySQL.Password != "pass${word}" {
+		t.Errorf("escaped reference not kept, got %q", cfg.ApiGatewaySQL.Password)
+	}
+}
+
+func TestLoadConfig_ReturnErrorWithUnresolvedReference(t *testing.T) {
+	t.Setenv("DB_USERNAME", "school_user")
+	t.Setenv("GATEWAY_PASSWORD", "gateway-password")
+
+	testCases := []struct {
+		name         string
+		authPassword string
+		dbPassword   string
+		path         string
+	}{
+		{"missing environment variable", "${GATEWAY_PASSWORD}", "${MISSING_DB_PASSWORD}", "api_gateway_sql.databases[0].password"},
+		{"missing secret file", "file:/nonexistent/secret", "password", "api_gateway_sql.auth.password"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(subT *testing.T) {
+			_, err := loadInterpolatedConfig(subT, testCase.authPassword, testCase.dbPassword)
+			if err == nil {
+				subT.Fatal("no error returned for unresolved reference")
+			}
+
+			if !strings.Contains(err.Error(), testCase.path) {
+				subT.Errorf("error %q does not name the config path %s", err, testCase.path)
+			}
+		})
+	}
+}
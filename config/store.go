@@ -0,0 +1,82 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot describes the configuration currently loaded in a Store
+type Snapshot struct {
+	Version  int64     `json:"version"`
+	Hash     string    `json:"hash"`
+	LoadedAt time.Time `json:"loaded_at"`
+	config   *Config
+}
+
+// Store holds the loaded configuration and allows to swap it atomically
+type Store struct {
+	current   atomic.Pointer[Snapshot]
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+func NewStore(config *Config, hash string) *Store {
+	store := &Store{}
+	store.current.Store(&Snapshot{Version: 1, Hash: hash, LoadedAt: time.Now(), config: config})
+
+	return store
+}
+
+// Get returns the configuration currently loaded
+func (s *Store) Get() *Config {
+	return s.current.Load().config
+}
+
+// Snapshot returns the version and hash of the configuration currently loaded
+func (s *Store) Snapshot() Snapshot {
+	return *s.current.Load()
+}
+
+// Swap replaces the loaded configuration and notifies the registered listeners
+func (s *Store) Swap(config *Config, hash string) Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := &Snapshot{
+		Version:  s.current.Load().Version + 1,
+		Hash:     hash,
+		LoadedAt: time.Now(),
+		config:   config,
+	}
+	s.current.Store(snapshot)
+
+	for _, listener := range s.listeners {
+		listener(config)
+	}
+
+	return *snapshot
+}
+
+// OnSwap registers a function called with the new configuration after each swap
+func (s *Store) OnSwap(listener func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// HashFile returns the sha256 hash of a configuration file
+func HashFile(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash config file: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// Watcher reloads the configuration file into a Store each time it changes
+type Watcher struct {
+	viperInstance *viper.Viper
+	validate      *validator.Validate
+	store         *Store
+}
+
+func NewWatcher(viperInstance *viper.Viper, validate *validator.Validate, store *Store) *Watcher {
+	return &Watcher{viperInstance, validate, store}
+}
+
+// Start watches the configuration file, onReload is called after each reload attempt
+func (w *Watcher) Start(onReload func(snapshot Snapshot, changed bool, err error)) {
+	w.viperInstance.OnConfigChange(func(_ fsnotify.Event) {
+		onReload(w.Reload())
+	})
+	w.viperInstance.WatchConfig()
+}
+
+// Reload reads and validates the configuration file again.
+// The store keeps its current configuration when the file is unchanged or invalid.
+func (w *Watcher) Reload() (Snapshot, bool, error) {
+	filename := w.viperInstance.ConfigFileUsed()
+	current := w.store.Snapshot()
+
+	hash, err := HashFile(filename)
+	if err != nil {
+		return current, false, err
+	}
+
+	if hash == current.Hash {
+		return current, false, nil
+	}
+
+	viperInstance, err := ReadConfigFile(filename)
+	if err != nil {
+		return current, false, err
+	}
+
+	config, err := LoadConfig(viperInstance, w.validate)
+	if err != nil {
+		return current, false, err
+	}
+
+	return w.store.Swap(config, hash), true, nil
+}
@@ -0,0 +1,128 @@
+package config_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const watchedConfig string = `---
+api_gateway_sql:
+  sqlitedb: "/data/api_gateway_sql"
+  auth:
+    enabled: true
+    username: "xxxxx"
+    password: xxxxxxxx
+  databases:
+  - name: "school"
+    type: "sqlite"
+    dbname: "/tmp/school"
+    timeout: "10s"
+  targets:
+  - name: "list-student"
+    data_source_name: "school"
+    sql: "select * from student"
+`
+
+func newWatcher(t *testing.T, content string) (string, *config.Store, *config.Watcher) {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	v, err := config.ReadConfigFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	cfg, err := config.LoadConfig(v, validate)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	hash, err := config.HashFile(filename)
+	if err != nil {
+		t.Fatalf("failed to hash config: %v", err)
+	}
+
+	store := config.NewStore(cfg, hash)
+	return filename, store, config.NewWatcher(v, validate, store)
+}
+
+func TestWatcherReload_UnchangedFileKeepsVersion(t *testing.T) {
+	t.Parallel()
+
+	_, store, watcher := newWatcher(t, watchedConfig)
+
+	snapshot, changed, err := watcher.Reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if changed || snapshot.Version != 1 || store.Snapshot().Version != 1 {
+		t.Errorf("unchanged file must not bump the version, got %d", snapshot.Version)
+	}
+}
+
+func TestWatcherReload_ValidFileIsSwapped(t *testing.T) {
+	t.Parallel()
+
+	filename, store, watcher := newWatcher(t, watchedConfig)
+
+	var notified *config.Config
+	store.OnSwap(func(cfg *config.Config) { notified = cfg })
+
+	updated := watchedConfig + `  - name: "list-school"
+    data_source_name: "school"
+    sql: "select * from school"
+`
+	if err := os.WriteFile(filename, []byte(updated), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	snapshot, changed, err := watcher.Reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !changed || snapshot.Version != 2 {
+		t.Errorf("valid file must be swapped, got version %d", snapshot.Version)
+	}
+
+	if _, found := store.Get().GetTargetByName("list-school"); !found {
+		t.Error("new target not found in the store")
+	}
+
+	if notified != store.Get() {
+		t.Error("listener not notified with the new configuration")
+	}
+}
+
+func TestWatcherReload_InvalidFileKeepsCurrentConfig(t *testing.T) {
+	t.Parallel()
+
+	filename, store, watcher := newWatcher(t, watchedConfig)
+	previous := store.Get()
+
+	invalid := watchedConfig + `  - name: ""
+`
+	if err := os.WriteFile(filename, []byte(invalid), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	snapshot, changed, err := watcher.Reload()
+	if err == nil {
+		t.Fatal("no error returned for invalid config")
+	}
+
+	if changed || snapshot.Version != 1 || store.Get() != previous {
+		t.Error("invalid file must not replace the current configuration")
+	}
+}
@@ -22,13 +22,102 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api-gateway-sql/batchstats": {
+        "/api-gateway-sql/_admin/config": {
             "get": {
+                "description": "Get the version and hash of the configuration currently loaded",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get loaded configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpresponse.HTTPResp"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/config.Snapshot"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
+                ]
+            }
+        },
+        "/api-gateway-sql/_transaction": {
+            "post": {
+                "description": "Run targets of a same datasource in order in a single transaction, a failure rolls back every target",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apisql"
+                ],
+                "summary": "Execute several targets in a single transaction",
+                "parameters": [
+                    {
+                        "description": "Targets to run, in order",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/httphandler.TransactionStep"
+                            }
+                        }
+                    }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
+            }
+        },
+        "/api-gateway-sql/batchstats": {
+            "get": {
                 "description": "Get a paginated list of BatchStat",
                 "consumes": [
                     "application/json"
@@ -75,16 +164,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
-            }
-        },
-        "/api-gateway-sql/batchstats/{uid}": {
-            "get": {
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/api-gateway-sql/batchstats/{uid}": {
+            "get": {
                 "description": "Get a BatchStat by uid",
                 "consumes": [
                     "application/json"
@@ -118,16 +207,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
-            }
-        },
-        "/api-gateway-sql/batchstats/{uid}/blocks": {
-            "get": {
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/api-gateway-sql/batchstats/{uid}/blocks": {
+            "get": {
                 "description": "Get a paginated list of Blocks By a BatchStat",
                 "consumes": [
                     "application/json"
@@ -181,16 +270,68 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
             }
         },
-        "/api-gateway-sql/batchstats/{uid}/completed": {
-            "get": {
+        "/api-gateway-sql/batchstats/{uid}/cancel": {
+            "post": {
+                "description": "Stop reading the csv file of a running batch, its running transactions commit or roll back before it is marked cancelled with its last line",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apisql"
+                ],
+                "summary": "Cancel a running BatchStat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "uid",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/api-gateway-sql/batchstats/{uid}/completed": {
+            "get": {
                 "description": "Mark completed a BatchStat by uid",
                 "consumes": [
                     "application/json"
@@ -224,16 +365,125 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
             }
         },
-        "/api-gateway-sql/blocks/{uid}": {
+        "/api-gateway-sql/batchstats/{uid}/rejects": {
             "get": {
+                "description": "Get the csv lines of a batch rejected on their own, with their line number, their values and the database error",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "apisql"
+                ],
+                "summary": "Download the rejected rows of a BatchStat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "uid",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
+                ]
+            }
+        },
+        "/api-gateway-sql/batchstats/{uid}/retry": {
+            "post": {
+                "description": "Run again the failure ranges of a completed batch, read from its staged csv file, optionally with a corrected sql query",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apisql"
+                ],
+                "summary": "Retry the failed lines of a BatchStat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "uid",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Corrected sql query of the target",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/httphandler.BatchRetryRequest"
+                        }
+                    }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
+            }
+        },
+        "/api-gateway-sql/blocks/{uid}": {
+            "get": {
                 "description": "Get a Block by uid",
                 "consumes": [
                     "application/json"
@@ -267,16 +517,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
-            }
-        },
-        "/api-gateway-sql/{datasource}/init": {
-            "post": {
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/api-gateway-sql/{datasource}/init": {
+            "post": {
                 "description": "Initialize Database by providing a sql query file",
                 "consumes": [
                     "application/json"
@@ -323,22 +573,243 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
             }
         },
-        "/api-gateway-sql/{target}": {
+        "/api-gateway-sql/{datasource}/migrations": {
             "get": {
+                "description": "List the migrations of the datasource migrations_dir and the migrations applied on the datasource",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "migrations"
+                ],
+                "summary": "Get migrations status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Datasource Name",
+                        "name": "datasource",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpresponse.HTTPResp"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dto.MigrationStatus"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
+                ]
+            }
+        },
+        "/api-gateway-sql/{datasource}/migrations/down": {
+            "post": {
+                "description": "Roll back the last applied migrations, with the down scripts of the uploaded files or the datasource migrations_dir",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "migrations"
+                ],
+                "summary": "Roll back migrations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Datasource Name",
+                        "name": "datasource",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Number of migrations to roll back",
+                        "name": "steps",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "NNN_name.up.sql and NNN_name.down.sql files",
+                        "name": "migrations",
+                        "in": "formData"
+                    }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpresponse.HTTPResp"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dto.MigrationStatus"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
+            }
+        },
+        "/api-gateway-sql/{datasource}/migrations/up": {
+            "post": {
+                "description": "Apply the pending migrations up to a version, from the uploaded files or the datasource migrations_dir",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "migrations"
+                ],
+                "summary": "Apply migrations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Datasource Name",
+                        "name": "datasource",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last version to apply, all pending migrations when omitted",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "NNN_name.up.sql and NNN_name.down.sql files",
+                        "name": "migrations",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpresponse.HTTPResp"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dto.MigrationStatus"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpresponse.HTTPResp"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
+            }
+        },
+        "/api-gateway-sql/{target}": {
+            "get": {
                 "description": "Trigger SQL query without params",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/csv",
+                    "application/xml",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
                     "apisql"
@@ -351,6 +822,60 @@ const docTemplate = `{
                         "name": "target",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson",
+                            "csv",
+                            "xlsx",
+                            "xml"
+                        ],
+                        "type": "string",
+                        "description": "Result format, the Accept header or the target default_format otherwise",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Stream the rows as they are read",
+                        "name": "stream",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number of a paginated target",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size of a paginated target, the target default_size otherwise",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Next page cursor of a target paginated on a cursor column",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Keep the rows whose filterable column equals the value, filter[column][operator] compares with eq, ne, lt, lte, gt, gte or like",
+                        "name": "filter[column]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated sortable columns, prefixed by - for the descending order",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -372,20 +897,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
-            },
-            "post": {
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
-                ],
+                ]
+            },
+            "post": {
                 "description": "Trigger SQL query with params",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/csv",
+                    "application/xml",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
                     "apisql"
@@ -408,6 +937,60 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson",
+                            "csv",
+                            "xlsx",
+                            "xml"
+                        ],
+                        "type": "string",
+                        "description": "Result format, the Accept header or the target default_format otherwise",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Stream the rows as they are read",
+                        "name": "stream",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number of a paginated target",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size of a paginated target, the target default_size otherwise",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Next page cursor of a target paginated on a cursor column",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Keep the rows whose filterable column equals the value, filter[column][operator] compares with eq, ne, lt, lte, gt, gte or like",
+                        "name": "filter[column]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated sortable columns, prefixed by - for the descending order",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -429,16 +1012,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
-            }
-        },
-        "/api-gateway-sql/{target}/batch": {
-            "post": {
+                },
                 "security": [
                     {
                         "BasicAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/api-gateway-sql/{target}/batch": {
+            "post": {
                 "description": "Execute batch sql query with values from a csv file",
                 "consumes": [
                     "application/json"
@@ -485,7 +1068,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httpresponse.HTTPResp"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ]
             }
         },
         "/healthz": {
@@ -513,6 +1101,60 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "config.Snapshot": {
+            "type": "object",
+            "properties": {
+                "hash": {
+                    "type": "string"
+                },
+                "loaded_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.MigrationStatus": {
+            "type": "object",
+            "properties": {
+                "applied_at": {
+                    "type": "integer"
+                },
+                "checksum": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "httphandler.BatchRetryRequest": {
+            "type": "object",
+            "properties": {
+                "sql": {
+                    "type": "string"
+                }
+            }
+        },
+        "httphandler.TransactionStep": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
         "httpresponse.HTTPResp": {
             "type": "object",
             "properties": {
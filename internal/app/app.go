@@ -9,15 +9,20 @@ import (
 	"github.com/willbrid/api-gateway-sql/internal/repository"
 	"github.com/willbrid/api-gateway-sql/internal/usecase"
 	"github.com/willbrid/api-gateway-sql/pkg/database"
+	"github.com/willbrid/api-gateway-sql/pkg/database/external"
 	"github.com/willbrid/api-gateway-sql/pkg/httpserver"
+	"github.com/willbrid/api-gateway-sql/pkg/staging"
 
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
-func Run(cfgfile *config.Config, cfgflag *config.ConfigFlag, logger zerolog.Logger) {
+func Run(cfgstore *config.Store, cfgwatcher *config.Watcher, cfgflag *config.ConfigFlag, logger zerolog.Logger) {
+	cfgfile := cfgstore.Get()
+
 	sqliteAppDatabase, err := database.NewSqliteAppDatabase(cfgfile.ApiGatewaySQL.Sqlitedb)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to init database server")
@@ -29,13 +34,33 @@ func Run(cfgfile *config.Config, cfgflag *config.ConfigFlag, logger zerolog.Logg
 		return
 	}
 
+	stagingStore, err := staging.NewStore(cfgfile.ApiGatewaySQL.StagingDir)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to init staging directory")
+		return
+	}
+
+	datasources := external.NewRegistry(cfgfile.ApiGatewaySQL.Databases)
+	cfgstore.OnSwap(func(cfg *config.Config) {
+		if err := datasources.Reload(cfg.ApiGatewaySQL.Databases); err != nil {
+			logger.Error().Err(err).Msg("failed to reload datasources")
+		}
+	})
+
 	repos := repository.NewRepositories(sqliteAppDatabase.Db, logger)
 	usecases := usecase.NewUsecases(usecase.Deps{
-		Repos:  repos,
-		Config: cfgfile,
-		Logger: logger,
+		Repos:       repos,
+		Datasources: datasources,
+		Staging:     stagingStore,
+		Config:      cfgstore,
+		Logger:      logger,
 	})
 
+	// the batches left running by a stopped process are marked failed
+	if _, err := usecases.IBatchStatUsecase.FailStaleBatches(context.Background(), cfgfile.ApiGatewaySQL.Batches.StaleAfter); err != nil {
+		logger.Error().Err(err).Msg("failed to mark stale batches failed")
+	}
+
 	httpServer := httpserver.NewServer(
 		fmt.Sprint(":"+fmt.Sprint(cfgflag.ListenPort)),
 		cfgflag.EnableHttps,
@@ -44,9 +69,20 @@ func Run(cfgfile *config.Config, cfgflag *config.ConfigFlag, logger zerolog.Logg
 	)
 	authMiddleware := middleware.NewAuthMiddleware(logger)
 	handlers := delivery.NewHandler(usecases, httpServer, authMiddleware, logger)
-	handlers.InitRouter(cfgfile, cfgflag)
+	handlers.InitRouter(cfgstore, cfgflag)
 	httpServer.Start()
 
+	cfgwatcher.Start(func(snapshot config.Snapshot, changed bool, err error) {
+		if err != nil {
+			logger.Error().Err(err).Msg("configuration file reload rejected, keeping the current configuration")
+			return
+		}
+
+		if changed {
+			logger.Info().Int64("version", snapshot.Version).Str("hash", snapshot.Hash).Msg("configuration file reloaded")
+		}
+	})
+
 	scheme := map[bool]string{true: "https", false: "http"}[cfgflag.EnableHttps]
 	logger.Info().Str("scheme", scheme).Int("port", cfgflag.ListenPort).Msg("app server starting")
 
@@ -67,4 +103,8 @@ func Run(cfgfile *config.Config, cfgflag *config.ConfigFlag, logger zerolog.Logg
 	if err := httpServer.Stop(); err != nil {
 		logger.Error().Err(err).Msg("app server stopping")
 	}
+
+	if err := datasources.Close(); err != nil {
+		logger.Error().Err(err).Msg("failed to close datasource connections")
+	}
 }
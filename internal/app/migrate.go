@@ -9,10 +9,24 @@ import (
 )
 
 func MigrateAppDatabase(db *gorm.DB) error {
-	err := db.AutoMigrate(&domain.BatchStat{}, &domain.Block{}, &domain.FailureRange{})
+	err := db.AutoMigrate(&domain.BatchStat{}, &domain.Block{}, &domain.FailureRange{}, &domain.RejectedRow{})
 	if err != nil {
 		return fmt.Errorf("failed to execute migration: %w", err)
 	}
 
+	if err := migrateFailureRanges(db); err != nil {
+		return fmt.Errorf("failed to migrate failure ranges: %w", err)
+	}
+
 	return nil
 }
+
+// migrateFailureRanges numbers by record of the csv file the failure ranges numbered from 0 in their block with
+// an excluded end line, as recorded before
+func migrateFailureRanges(db *gorm.DB) error {
+	return db.Exec(`UPDATE failure_ranges SET
+		start_line = (SELECT blocks.start_line FROM blocks WHERE blocks.id = failure_ranges.block_id) + start_line,
+		end_line = (SELECT blocks.start_line FROM blocks WHERE blocks.id = failure_ranges.block_id) + end_line - 1,
+		file_lines = true
+		WHERE file_lines = false AND block_id IN (SELECT id FROM blocks)`).Error
+}
@@ -0,0 +1,61 @@
+package app_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/app"
+	"github.com/willbrid/api-gateway-sql/internal/domain"
+
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrateAppDatabase_NumbersFailureRangesByFileLine(t *testing.T) {
+	t.Parallel()
+
+	appDb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "app.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open app database: %v", err)
+	}
+	t.Cleanup(func() {
+		if cnx, err := appDb.DB(); err == nil {
+			_ = cnx.Close()
+		}
+	})
+
+	if err := app.MigrateAppDatabase(appDb); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	block := domain.NewBlock(101, 200)
+	if err := appDb.Create(block).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a range recorded before, numbered from 0 in its block with an excluded end line
+	if err := appDb.Exec("INSERT INTO failure_ranges (id, start_line, end_line, file_lines, block_id) VALUES ('old', 10, 20, false, ?)", block.ID).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current := domain.NewFailureRange(150, 160)
+	current.BlockID = block.ID
+	if err := appDb.Create(current).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// migrating twice converts the old range once
+	for range 2 {
+		if err := app.MigrateAppDatabase(appDb); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for id, expected := range map[string][2]int{"old": {111, 120}, current.ID: {150, 160}} {
+		var failureRange domain.FailureRange
+		if err := appDb.First(&failureRange, "id = ?", id).Error; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := [2]int{failureRange.StartLine, failureRange.EndLine}; got != expected {
+			t.Errorf("got range %v for %s, want %v", got, id, expected)
+		}
+	}
+}
@@ -26,14 +26,14 @@ func NewHandler(usecases *usecase.Usecases, iServer httpserver.IServer, iAuthMid
 	return &Handler{usecases, iServer, iAuthMiddleware, logger}
 }
 
-func (h *Handler) InitRouter(cfg *config.Config, cfgflag *config.ConfigFlag) {
+func (h *Handler) InitRouter(store *config.Store, cfgflag *config.ConfigFlag) {
 	router := h.iServer.GetRouter()
 	router.Use(func(subH http.Handler) http.Handler {
 		authMiddleware := middleware.NewAuthMiddleware(h.logger)
-		return authMiddleware.Authenticate(subH, cfg)
+		return authMiddleware.Authenticate(subH, store)
 	})
 
-	if cfg.ApiGatewaySQL.EnableSwagger {
+	if store.Get().ApiGatewaySQL.EnableSwagger {
 		scheme := map[bool]string{true: "https", false: "http"}[cfgflag.EnableHttps]
 		swaggerUrl := fmt.Sprintf("%s://localhost:%d/swagger/doc.json", scheme, cfgflag.ListenPort)
 
@@ -45,16 +45,24 @@ func (h *Handler) InitRouter(cfg *config.Config, cfgflag *config.ConfigFlag) {
 		)).Methods("GET")
 	}
 
-	httphandler := httphandler.NewHTTPHandler(h.Usecases, cfg, h.logger)
+	httphandler := httphandler.NewHTTPHandler(h.Usecases, store, h.logger)
 
 	router.HandleFunc("/healthz", httphandler.HandleHealthCheck).Methods("GET")
+	router.HandleFunc("/api-gateway-sql/_admin/config", httphandler.ApiGetConfigHandler).Methods("GET")
+	router.HandleFunc("/api-gateway-sql/_transaction", httphandler.ApiPostTransactionHandler).Methods("POST")
 	router.HandleFunc("/api-gateway-sql/blocks/{uid}", httphandler.ApiGetBlockHandler).Methods("GET")
 	router.HandleFunc("/api-gateway-sql/batchstats", httphandler.ApiListBatchStatsHandler).Methods("GET")
 	router.HandleFunc("/api-gateway-sql/batchstats/{uid}", httphandler.ApiGetBatchStatHandler).Methods("GET")
 	router.HandleFunc("/api-gateway-sql/batchstats/{uid}/blocks", httphandler.ApiListBlocksByBatchStatHandler).Methods("GET")
 	router.HandleFunc("/api-gateway-sql/batchstats/{uid}/completed", httphandler.ApiMarkCompletedBatchStatHandler).Methods("GET")
+	router.HandleFunc("/api-gateway-sql/batchstats/{uid}/retry", httphandler.ApiRetryBatchStatHandler).Methods("POST")
+	router.HandleFunc("/api-gateway-sql/batchstats/{uid}/cancel", httphandler.ApiCancelBatchStatHandler).Methods("POST")
+	router.HandleFunc("/api-gateway-sql/batchstats/{uid}/rejects", httphandler.ApiGetBatchStatRejectsHandler).Methods("GET")
 	router.HandleFunc("/api-gateway-sql/{target}", httphandler.ApiGetSqlHandler).Methods("GET")
 	router.HandleFunc("/api-gateway-sql/{target}", httphandler.ApiPostSqlHandler).Methods("POST")
 	router.HandleFunc("/api-gateway-sql/{target}/batch", httphandler.ApiPostSqlBatchHandler).Methods("POST")
 	router.HandleFunc("/api-gateway-sql/{datasource}/init", httphandler.ApiPostInitDatabase).Methods("POST")
+	router.HandleFunc("/api-gateway-sql/{datasource}/migrations", httphandler.ApiGetMigrationsHandler).Methods("GET")
+	router.HandleFunc("/api-gateway-sql/{datasource}/migrations/up", httphandler.ApiPostMigrationsUpHandler).Methods("POST")
+	router.HandleFunc("/api-gateway-sql/{datasource}/migrations/down", httphandler.ApiPostMigrationsDownHandler).Methods("POST")
 }
@@ -0,0 +1,20 @@
+package httphandler
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/delivery/httpresponse"
+
+	"net/http"
+)
+
+// ApiGetConfigHandler godoc
+// @Summary      Get loaded configuration
+// @Description  Get the version and hash of the configuration currently loaded
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  httpresponse.HTTPResp{data=config.Snapshot}
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/_admin/config [get]
+func (h *HTTPHandler) ApiGetConfigHandler(resp http.ResponseWriter, req *http.Request) {
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, h.cfg.Snapshot())
+}
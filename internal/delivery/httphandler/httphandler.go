@@ -8,11 +8,11 @@ import (
 
 type HTTPHandler struct {
 	Usercases *usecase.Usecases
-	cfg       *config.Config
+	cfg       *config.Store
 	logger    zerolog.Logger
 }
 
-func NewHTTPHandler(usercases *usecase.Usecases, cfg *config.Config, logger zerolog.Logger) *HTTPHandler {
+func NewHTTPHandler(usercases *usecase.Usecases, cfg *config.Store, logger zerolog.Logger) *HTTPHandler {
 	return &HTTPHandler{
 		Usercases: usercases,
 		cfg:       cfg,
@@ -0,0 +1,175 @@
+package httphandler
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/delivery/httpresponse"
+	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/migration"
+
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	errUnableToReadMigrationFiles string = "unable to read the migration files"
+	errUnableToRunMigrations      string = "unable to run the migrations"
+	maxMigrationUploadMemory      int64  = 32 << 20
+)
+
+// ApiGetMigrationsHandler godoc
+// @Summary      Get migrations status
+// @Description  List the migrations of the datasource migrations_dir and the migrations applied on the datasource
+// @Tags         migrations
+// @Accept       json
+// @Produce      json
+// @Param        datasource  path  string  true  "Datasource Name"
+// @Success      200  {object}  httpresponse.HTTPResp{data=[]dto.MigrationStatus}
+// @Failure      400  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/{datasource}/migrations [get]
+func (h *HTTPHandler) ApiGetMigrationsHandler(resp http.ResponseWriter, req *http.Request) {
+	migrationInput := &dto.MigrationInput{Datasource: mux.Vars(req)["datasource"]}
+
+	statuses, err := h.Usercases.IMigrationUsecase.Status(req.Context(), migrationInput)
+	if err != nil {
+		h.logger.Error().Msgf("failed to get migrations status: %s", err.Error())
+		h.sendMigrationError(resp, err)
+		return
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, statuses)
+}
+
+// ApiPostMigrationsUpHandler godoc
+// @Summary      Apply migrations
+// @Description  Apply the pending migrations up to a version, from the uploaded files or the datasource migrations_dir
+// @Tags         migrations
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        datasource  path  string  true  "Datasource Name"
+// @Param        version  query  int  false  "Last version to apply, all pending migrations when omitted"
+// @Param        migrations  formData  file  false  "NNN_name.up.sql and NNN_name.down.sql files"
+// @Success      200  {object}  httpresponse.HTTPResp{data=[]dto.MigrationStatus}
+// @Failure      400  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/{datasource}/migrations/up [post]
+func (h *HTTPHandler) ApiPostMigrationsUpHandler(resp http.ResponseWriter, req *http.Request) {
+	var (
+		version int64
+		err     error
+	)
+
+	if value := req.URL.Query().Get("version"); value != "" {
+		version, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || version < 0 {
+			_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, "Unable to handle version", nil)
+			return
+		}
+	}
+
+	files, err := readMigrationFiles(req)
+	if err != nil {
+		h.logger.Error().Msgf("failed to read migration files: %s", err.Error())
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, errUnableToReadMigrationFiles, nil)
+		return
+	}
+
+	migrationInput := &dto.MigrationInput{Datasource: mux.Vars(req)["datasource"], Files: files, Version: version}
+
+	statuses, err := h.Usercases.IMigrationUsecase.Up(req.Context(), migrationInput)
+	if err != nil {
+		h.logger.Error().Msgf("failed to apply migrations: %s", err.Error())
+		h.sendMigrationError(resp, err)
+		return
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, successAPIMessage, statuses)
+}
+
+// ApiPostMigrationsDownHandler godoc
+// @Summary      Roll back migrations
+// @Description  Roll back the last applied migrations, with the down scripts of the uploaded files or the datasource migrations_dir
+// @Tags         migrations
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        datasource  path  string  true  "Datasource Name"
+// @Param        steps  query  int  false  "Number of migrations to roll back" default(1)
+// @Param        migrations  formData  file  false  "NNN_name.up.sql and NNN_name.down.sql files"
+// @Success      200  {object}  httpresponse.HTTPResp{data=[]dto.MigrationStatus}
+// @Failure      400  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/{datasource}/migrations/down [post]
+func (h *HTTPHandler) ApiPostMigrationsDownHandler(resp http.ResponseWriter, req *http.Request) {
+	steps := 1
+
+	if value := req.URL.Query().Get("steps"); value != "" {
+		var err error
+		steps, err = strconv.Atoi(value)
+		if err != nil || steps < 1 {
+			_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, "Unable to handle steps", nil)
+			return
+		}
+	}
+
+	files, err := readMigrationFiles(req)
+	if err != nil {
+		h.logger.Error().Msgf("failed to read migration files: %s", err.Error())
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, errUnableToReadMigrationFiles, nil)
+		return
+	}
+
+	migrationInput := &dto.MigrationInput{Datasource: mux.Vars(req)["datasource"], Files: files, Steps: steps}
+
+	statuses, err := h.Usercases.IMigrationUsecase.Down(req.Context(), migrationInput)
+	if err != nil {
+		h.logger.Error().Msgf("failed to roll back migrations: %s", err.Error())
+		h.sendMigrationError(resp, err)
+		return
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, successAPIMessage, statuses)
+}
+
+// readMigrationFiles returns the content of the uploaded migrations files by filename, a request without files is not an error
+func readMigrationFiles(req *http.Request) (map[string]string, error) {
+	if err := req.ParseMultipartForm(maxMigrationUploadMemory); err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	headers := req.MultipartForm.File["migrations"]
+	files := make(map[string]string, len(headers))
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[header.Filename] = string(content)
+	}
+
+	return files, nil
+}
+
+// sendMigrationError answers 400 when the migrations themselves are rejected, 500 otherwise
+func (h *HTTPHandler) sendMigrationError(resp http.ResponseWriter, err error) {
+	if errors.Is(err, migration.ErrInvalidMigration) || errors.Is(err, migration.ErrChecksumMismatch) || errors.Is(err, migration.ErrIrreversible) {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, errUnableToRunMigrations, nil)
+}
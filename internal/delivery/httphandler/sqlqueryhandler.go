@@ -1,26 +1,40 @@
 package httphandler
 
 import (
+	"github.com/willbrid/api-gateway-sql/config"
 	"github.com/willbrid/api-gateway-sql/internal/delivery/httpresponse"
 	"github.com/willbrid/api-gateway-sql/internal/dto"
 	"github.com/willbrid/api-gateway-sql/internal/dto/paginator"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/paramschema"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlscript"
+	"github.com/willbrid/api-gateway-sql/internal/usecase"
 
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
+var filterKeyRegex = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[(\w+)\])?$`)
+
 const (
 	successAPIMessage              string = "operation completed successfully"
 	failedAPIMessage               string = "operation ended in failure"
 	errUnableToReadSQLFile         string = "unable to read the sql file content"
 	errUnableToReadCSVFile         string = "unable to read the csv file content"
 	errUnableToExecuteInitSqlQuery string = "unable to execute the init sql query"
-	errThereIsAnUnCompletedBatch   string = "there is an uncompleted batch"
+	errInvalidParams               string = "invalid params"
 )
 
 // HandleHealthCheck godoc
@@ -41,8 +55,15 @@ func (h *HTTPHandler) HandleHealthCheck(resp http.ResponseWriter, req *http.Requ
 // @Description  Trigger SQL query without params
 // @Tags         apisql
 // @Accept       json
-// @Produce      json
+// @Produce      application/json,application/x-ndjson,text/csv,application/xml,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param        target  path  string  true  "Target Name"
+// @Param        format  query  string  false  "Result format, the Accept header or the target default_format otherwise" Enums(json, ndjson, csv, xlsx, xml)
+// @Param        stream  query  string  false  "Stream the rows as they are read" Enums(json, ndjson)
+// @Param        page_num  query  int  false  "Page number of a paginated target" default(1)
+// @Param        page_size  query  int  false  "Page size of a paginated target, the target default_size otherwise"
+// @Param        cursor  query  string  false  "Next page cursor of a target paginated on a cursor column"
+// @Param        filter[column]  query  string  false  "Keep the rows whose filterable column equals the value, filter[column][operator] compares with eq, ne, lt, lte, gt, gte or like"
+// @Param        sort  query  string  false  "Comma separated sortable columns, prefixed by - for the descending order"
 // @Success      200  {object}  httpresponse.HTTPResp
 // @Failure      400  {object}  httpresponse.HTTPResp
 // @Failure      500  {object}  httpresponse.HTTPResp
@@ -58,10 +79,32 @@ func (h *HTTPHandler) ApiGetSqlHandler(resp http.ResponseWriter, req *http.Reque
 		PostParams: make(map[string]any, 0),
 	}
 
+	filters, sorts, err := parseFilters(req.URL.Query())
+	if err != nil {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	sqlqueryInput.Filters, sqlqueryInput.Sorts = filters, sorts
+
+	writer, err := h.newStreamWriter(resp, req, targetName)
+	if err != nil {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// a paginated target only answers json pages, the streamed formats are refused for it
+	if target, exists := h.cfg.Get().GetTargetByName(targetName); exists && target.Pagination != nil && writer == nil {
+		h.pageSql(ctx, resp, req, target.Pagination, sqlqueryInput)
+		return
+	}
+	if writer != nil {
+		h.streamSql(ctx, resp, writer, sqlqueryInput)
+		return
+	}
+
 	sqlqueryOutput, err := h.Usercases.ISQLQueryUsecase.ExecuteSingle(ctx, sqlqueryInput)
 	if err != nil {
-		h.logger.Error().Msgf("failed to decode post params: %s", err.Error())
-		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, failedAPIMessage, nil)
+		h.logger.Error().Msgf("failed to execute single sql query: %s", err.Error())
+		h.sendExecutionError(resp, err)
 		return
 	}
 
@@ -73,9 +116,16 @@ func (h *HTTPHandler) ApiGetSqlHandler(resp http.ResponseWriter, req *http.Reque
 // @Description  Trigger SQL query with params
 // @Tags         apisql
 // @Accept       json
-// @Produce      json
+// @Produce      application/json,application/x-ndjson,text/csv,application/xml,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param        target path  string  true  "Target Name"
 // @Param        data  body  map[string]interface{}  true  "Data to send"
+// @Param        format  query  string  false  "Result format, the Accept header or the target default_format otherwise" Enums(json, ndjson, csv, xlsx, xml)
+// @Param        stream  query  string  false  "Stream the rows as they are read" Enums(json, ndjson)
+// @Param        page_num  query  int  false  "Page number of a paginated target" default(1)
+// @Param        page_size  query  int  false  "Page size of a paginated target, the target default_size otherwise"
+// @Param        cursor  query  string  false  "Next page cursor of a target paginated on a cursor column"
+// @Param        filter[column]  query  string  false  "Keep the rows whose filterable column equals the value, filter[column][operator] compares with eq, ne, lt, lte, gt, gte or like"
+// @Param        sort  query  string  false  "Comma separated sortable columns, prefixed by - for the descending order"
 // @Success      200  {object}  httpresponse.HTTPResp
 // @Failure      400  {object}  httpresponse.HTTPResp
 // @Failure      500  {object}  httpresponse.HTTPResp
@@ -98,16 +148,105 @@ func (h *HTTPHandler) ApiPostSqlHandler(resp http.ResponseWriter, req *http.Requ
 		PostParams: postParams,
 	}
 
+	filters, sorts, err := parseFilters(req.URL.Query())
+	if err != nil {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	sqlqueryInput.Filters, sqlqueryInput.Sorts = filters, sorts
+
+	writer, err := h.newStreamWriter(resp, req, targetName)
+	if err != nil {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// a paginated target only answers json pages, the streamed formats are refused for it
+	if target, exists := h.cfg.Get().GetTargetByName(targetName); exists && target.Pagination != nil && writer == nil {
+		h.pageSql(ctx, resp, req, target.Pagination, sqlqueryInput)
+		return
+	}
+	if writer != nil {
+		h.streamSql(ctx, resp, writer, sqlqueryInput)
+		return
+	}
+
 	sqlqueryOutput, err := h.Usercases.ISQLQueryUsecase.ExecuteSingle(ctx, sqlqueryInput)
 	if err != nil {
 		h.logger.Error().Msgf("failed to execute single sql query: %s", err.Error())
-		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, failedAPIMessage, nil)
+		h.sendExecutionError(resp, err)
 		return
 	}
 
 	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, successAPIMessage, sqlqueryOutput)
 }
 
+// TransactionStep is a target to run in a transaction, with its params
+type TransactionStep struct {
+	Target string         `json:"target"`
+	Params map[string]any `json:"params"`
+}
+
+// transactionFailure tells which target of a transaction failed, Step is its position in the request from 0
+type transactionFailure struct {
+	Step   int                      `json:"step"`
+	Target string                   `json:"target"`
+	Fields []paramschema.FieldError `json:"fields,omitempty"`
+}
+
+// ApiPostTransactionHandler godoc
+// @Summary      Execute several targets in a single transaction
+// @Description  Run targets of a same datasource in order in a single transaction, a failure rolls back every target
+// @Tags         apisql
+// @Accept       json
+// @Produce      json
+// @Param        data  body  []TransactionStep  true  "Targets to run, in order"
+// @Success      200  {object}  httpresponse.HTTPResp
+// @Failure      400  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/_transaction [post]
+func (h *HTTPHandler) ApiPostTransactionHandler(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var transactionSteps []TransactionStep
+	if err := json.NewDecoder(req.Body).Decode(&transactionSteps); err != nil {
+		h.logger.Error().Msgf("failed to decode transaction steps: %s", err.Error())
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	transactionInput := &dto.SQLTransactionInput{Steps: make([]dto.SQLQueryInput, len(transactionSteps))}
+	for idx, step := range transactionSteps {
+		transactionInput.Steps[idx] = dto.SQLQueryInput{TargetName: step.Target, PostParams: step.Params}
+	}
+
+	sqlqueryOutput, err := h.Usercases.ISQLQueryUsecase.ExecuteTransaction(ctx, transactionInput)
+	if err == nil {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusOK, successAPIMessage, sqlqueryOutput)
+		return
+	}
+
+	h.logger.Error().Msgf("failed to execute transaction: %s", err.Error())
+
+	var stepErr *dto.StepError
+	if !errors.As(err, &stepErr) {
+		h.sendExecutionError(resp, err)
+		return
+	}
+
+	failure := transactionFailure{Step: stepErr.Index, Target: stepErr.Name}
+	var validationErr *paramschema.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		failure.Fields = validationErr.Fields
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, errInvalidParams, failure)
+	case errors.Is(err, usecase.ErrInvalidTransaction) || errors.Is(err, sqlqueryhelper.ErrUnboundParam):
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, stepErr.Err.Error(), failure)
+	default:
+		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, failedAPIMessage, failure)
+	}
+}
+
 // ApiPostInitDatabase godoc
 // @Summary      Initialize Database
 // @Description  Initialize Database by providing a sql query file
@@ -147,6 +286,10 @@ func (h *HTTPHandler) ApiPostInitDatabase(resp http.ResponseWriter, req *http.Re
 
 	if err := h.Usercases.ISQLQueryUsecase.ExecuteInit(ctx, sqlInitDatabaseInput); err != nil {
 		h.logger.Error().Msgf("error: %s", err.Error())
+		if errors.Is(err, sqlscript.ErrBatchCount) {
+			_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
 		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, errUnableToExecuteInitSqlQuery, nil)
 		return
 	}
@@ -179,29 +322,27 @@ func (h *HTTPHandler) ApiPostSqlBatchHandler(resp http.ResponseWriter, req *http
 		return
 	}
 
-	isAllBatchStatClosed, err := h.Usercases.IBatchStatUsecase.IsAllBatchStatClosed(ctx)
-	if err != nil {
-		h.logger.Error().Msgf("an error occurred: %s", err.Error())
-		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, httpresponse.HTTPStatusInternalServerErrorMessage, nil)
-		return
-	} else if !isAllBatchStatClosed {
-		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, errThereIsAnUnCompletedBatch, nil)
-		return
-	}
-
 	sqlBatchQueryInput := &dto.SQLBatchQueryInput{
 		TargetName: targetName,
 		File:       csvfile,
 	}
 
+	batchRun, err := h.Usercases.ISQLBatchQueryUsecase.PrepareBatch(ctx, sqlBatchQueryInput)
+	if err != nil {
+		h.logger.Error().Msgf("failed to start batch: %s", err.Error())
+		h.sendBatchError(resp, err)
+		return
+	}
+
+	// the batch is answered before it runs, which updates it
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, batchRun.BSInput)
+
 	go func() {
 		ctx := context.Background()
-		if err := h.Usercases.ISQLBatchQueryUsecase.ExecuteBatch(ctx, sqlBatchQueryInput); err != nil {
+		if err := h.Usercases.ISQLBatchQueryUsecase.ExecuteBatch(ctx, batchRun); err != nil {
 			h.logger.Error().Msgf("failed to process batch: %s", err.Error())
 		}
 	}()
-
-	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, nil)
 }
 
 // ApiListBatchStatsHandler godoc
@@ -305,6 +446,141 @@ func (h *HTTPHandler) ApiMarkCompletedBatchStatHandler(resp http.ResponseWriter,
 	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, nil)
 }
 
+// BatchRetryRequest optionally replaces the sql of the target of a retried batch
+type BatchRetryRequest struct {
+	SQL string `json:"sql"`
+}
+
+// ApiRetryBatchStatHandler godoc
+// @Summary      Retry the failed lines of a BatchStat
+// @Description  Run again the failure ranges of a completed batch, read from its staged csv file, optionally with a corrected sql query
+// @Tags         apisql
+// @Accept       json
+// @Produce      json
+// @Param        uid path string  true  "uid"
+// @Param        data  body  BatchRetryRequest  false  "Corrected sql query of the target"
+// @Success      200  {object}  httpresponse.HTTPResp
+// @Failure      400  {object}  httpresponse.HTTPResp
+// @Failure      404  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/batchstats/{uid}/retry [post]
+func (h *HTTPHandler) ApiRetryBatchStatHandler(resp http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	uid := vars["uid"]
+	ctx := req.Context()
+
+	var retryRequest BatchRetryRequest
+	if err := json.NewDecoder(req.Body).Decode(&retryRequest); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error().Msgf("failed to decode retry request: %s", err.Error())
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	retry, err := h.Usercases.ISQLBatchQueryUsecase.PrepareRetry(ctx, &dto.SQLBatchRetryInput{BatchStatID: uid, SqlQuery: retryRequest.SQL})
+	if err != nil {
+		h.logger.Error().Msgf("failed to prepare batch retry: %s", err.Error())
+		h.sendBatchError(resp, err)
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+		if err := h.Usercases.ISQLBatchQueryUsecase.ExecuteRetry(ctx, retry); err != nil {
+			h.logger.Error().Msgf("failed to retry batch: %s", err.Error())
+		}
+	}()
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, nil)
+}
+
+// ApiCancelBatchStatHandler godoc
+// @Summary      Cancel a running BatchStat
+// @Description  Stop reading the csv file of a running batch, its running transactions commit or roll back before it is marked cancelled with its last line
+// @Tags         apisql
+// @Produce      json
+// @Param        uid path string  true  "uid"
+// @Success      200  {object}  httpresponse.HTTPResp
+// @Failure      400  {object}  httpresponse.HTTPResp
+// @Failure      404  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/batchstats/{uid}/cancel [post]
+func (h *HTTPHandler) ApiCancelBatchStatHandler(resp http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	uid := vars["uid"]
+	ctx := req.Context()
+
+	if err := h.Usercases.ISQLBatchQueryUsecase.CancelBatch(ctx, uid); err != nil {
+		h.logger.Error().Msgf("failed to cancel batch: %s", err.Error())
+		h.sendBatchError(resp, err)
+		return
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, nil)
+}
+
+// ApiGetBatchStatRejectsHandler godoc
+// @Summary      Download the rejected rows of a BatchStat
+// @Description  Get the csv lines of a batch rejected on their own, with their line number, their values and the database error
+// @Tags         apisql
+// @Produce      text/csv
+// @Param        uid path string  true  "uid"
+// @Success      200  {file}    file
+// @Failure      404  {object}  httpresponse.HTTPResp
+// @Failure      500  {object}  httpresponse.HTTPResp
+// @Security     BasicAuth
+// @Router       /api-gateway-sql/batchstats/{uid}/rejects [get]
+func (h *HTTPHandler) ApiGetBatchStatRejectsHandler(resp http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	uid := vars["uid"]
+	ctx := req.Context()
+
+	rejects, err := h.Usercases.ISQLBatchQueryUsecase.Rejects(ctx, uid)
+	if errors.Is(err, usecase.ErrBatchNotFound) {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Msgf("failed to get rejected rows: %s", err.Error())
+		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, httpresponse.HTTPStatusInternalServerErrorMessage, nil)
+		return
+	}
+
+	writer := httpresponse.NewStreamWriter(resp, httpresponse.FormatCSV, successAPIMessage, "rejects-"+uid)
+	columns := append(append([]string{"line"}, rejects.Fields...), "error")
+	if err := writer.WriteColumns(columns); err != nil {
+		h.logger.Error().Msgf("failed to write rejected rows: %s", err.Error())
+		return
+	}
+
+	for _, reject := range rejects.Rows {
+		var values []string
+		if err := json.Unmarshal([]byte(reject.Values), &values); err != nil {
+			h.logger.Error().Msgf("failed to decode rejected row values: %s", err.Error())
+		}
+
+		// values beyond the batch fields of the target are dropped, missing ones left empty
+		row := make([]any, len(columns))
+		row[0] = reject.Line
+		for idx := range rejects.Fields {
+			if idx < len(values) {
+				row[idx+1] = values[idx]
+			}
+		}
+		row[len(columns)-1] = reject.Error
+
+		if err := writer.WriteRow(row); err != nil {
+			h.logger.Error().Msgf("failed to write rejected rows: %s", err.Error())
+			return
+		}
+	}
+
+	if err := writer.Close(int64(len(rejects.Rows)), ""); err != nil {
+		h.logger.Error().Msgf("failed to end rejected rows response: %s", err.Error())
+	}
+}
+
 // ApiListBlocksByBatchStatsHandler godoc
 // @Summary      Get Blocks result
 // @Description  Get a paginated list of Blocks By a BatchStat
@@ -381,3 +657,207 @@ func (h *HTTPHandler) ApiGetBlockHandler(resp http.ResponseWriter, req *http.Req
 
 	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, httpresponse.HTTPStatusOKMessage, block)
 }
+
+// newStreamWriter returns a writer when the rows are asked in another format than the json envelope, or asked
+// streamed with the stream query parameter (json or ndjson). The format is taken from the format query parameter,
+// the Accept header or the target default_format. It returns nil for the json envelope.
+func (h *HTTPHandler) newStreamWriter(resp http.ResponseWriter, req *http.Request, targetName string) (*httpresponse.StreamWriter, error) {
+	defaultFormat := ""
+	if target, exists := h.cfg.Get().GetTargetByName(targetName); exists {
+		defaultFormat = target.DefaultFormat
+	}
+
+	format, err := httpresponse.NegotiateFormat(req, defaultFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := req.URL.Query()
+	stream := queries.Get("stream")
+	if stream == httpresponse.FormatNDJSON && queries.Get("format") == "" {
+		format = httpresponse.FormatNDJSON
+	}
+
+	if format == httpresponse.FormatJSON && stream != httpresponse.FormatJSON {
+		return nil, nil
+	}
+
+	return httpresponse.NewStreamWriter(resp, format, successAPIMessage, targetName), nil
+}
+
+// streamSql writes the target rows as they are read. Errors before the first row get a normal error response,
+// later errors can only be reported at the end of the stream.
+func (h *HTTPHandler) streamSql(ctx context.Context, resp http.ResponseWriter, writer *httpresponse.StreamWriter, sqlqueryInput *dto.SQLQueryInput) {
+	count, err := h.Usercases.ISQLQueryUsecase.StreamSingle(ctx, sqlqueryInput, writer)
+	if err != nil {
+		h.logger.Error().Msgf("failed to stream single sql query: %s", err.Error())
+		if !writer.Started() {
+			h.sendExecutionError(resp, err)
+			return
+		}
+	}
+
+	failure := ""
+	if err != nil {
+		failure = failedAPIMessage
+	}
+
+	if err := writer.Close(count, failure); err != nil {
+		h.logger.Error().Msgf("failed to end streamed response: %s", err.Error())
+	}
+}
+
+// pageSql answers the rows of a page of a paginated target, with Link headers to the neighbour pages
+func (h *HTTPHandler) pageSql(ctx context.Context, resp http.ResponseWriter, req *http.Request, pagination *config.Pagination, sqlqueryInput *dto.SQLQueryInput) {
+	queries := req.URL.Query()
+
+	pageNum := 1
+	if value := queries.Get("page_num"); value != "" && pagination.CursorColumn == "" {
+		var err error
+		if pageNum, err = strconv.Atoi(value); err != nil || pageNum < 1 {
+			h.logger.Error().Msgf("failed to handle page_num param query: %s", value)
+			_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, "Unable to handle page_num", nil)
+			return
+		}
+	}
+
+	pageSize := pagination.DefaultSize
+	if value := queries.Get("page_size"); value != "" {
+		var err error
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 1 {
+			h.logger.Error().Msgf("failed to handle page_size param query: %s", value)
+			_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, "Unable to handle page_size", nil)
+			return
+		}
+	}
+	pageSize = min(pageSize, pagination.MaxSize)
+
+	pageRequest := paginator.NewPageRequest(pageNum, pageSize)
+	if pagination.CursorColumn != "" {
+		pageRequest.PageNum = 0
+		pageRequest.Cursor = queries.Get("cursor")
+	}
+
+	pageResponse, err := h.Usercases.ISQLQueryUsecase.ExecutePage(ctx, sqlqueryInput, pageRequest)
+	if err != nil {
+		h.logger.Error().Msgf("failed to execute page sql query: %s", err.Error())
+		h.sendExecutionError(resp, err)
+		return
+	}
+
+	for _, link := range pageLinks(req, pageResponse, pagination.CursorColumn != "") {
+		resp.Header().Add("Link", link)
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusOK, successAPIMessage, pageResponse)
+}
+
+// pageLinks returns the first, prev, next and last links of a numbered page,
+// or the first and next links of a keyset page
+func pageLinks(req *http.Request, pageResponse *paginator.PageResponse, keyset bool) []string {
+	link := func(rel string, values map[string]string) string {
+		queries := req.URL.Query()
+		queries.Set("page_size", strconv.Itoa(pageResponse.PageSize))
+		queries.Del("page_num")
+		queries.Del("cursor")
+		for key, value := range values {
+			queries.Set(key, value)
+		}
+
+		return "<" + req.URL.Path + "?" + queries.Encode() + `>; rel="` + rel + `"`
+	}
+
+	if keyset {
+		links := []string{link("first", nil)}
+		if pageResponse.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"cursor": pageResponse.NextCursor}))
+		}
+		return links
+	}
+
+	page := func(rel string, pageNum int64) string {
+		return link(rel, map[string]string{"page_num": strconv.FormatInt(pageNum, 10)})
+	}
+
+	links := []string{page("first", 1)}
+	current := int64(pageResponse.PageNum)
+	if current > 1 {
+		links = append(links, page("prev", min(current-1, max(pageResponse.TotalPages, 1))))
+	}
+	if current < pageResponse.TotalPages {
+		links = append(links, page("next", current+1))
+	}
+
+	return append(links, page("last", max(pageResponse.TotalPages, 1)))
+}
+
+// parseFilters reads the filter[column]=value, filter[column][operator]=value and sort=column,-column query parameters
+func parseFilters(queries url.Values) ([]dto.Filter, []dto.Sort, error) {
+	var filters []dto.Filter
+
+	keys := slices.Sorted(maps.Keys(queries))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+
+		matches := filterKeyRegex.FindStringSubmatch(key)
+		if matches == nil {
+			return nil, nil, fmt.Errorf("%w: malformed %s query parameter", usecase.ErrInvalidFilter, key)
+		}
+
+		operator := matches[2]
+		if operator == "" {
+			operator = "eq"
+		}
+		for _, value := range queries[key] {
+			filters = append(filters, dto.Filter{Column: matches[1], Operator: operator, Value: value})
+		}
+	}
+
+	var sorts []dto.Sort
+	for _, value := range queries["sort"] {
+		for _, column := range strings.Split(value, ",") {
+			column = strings.TrimSpace(column)
+			descending := strings.HasPrefix(column, "-")
+			column = strings.TrimLeft(column, "+-")
+			if column == "" {
+				continue
+			}
+
+			sorts = append(sorts, dto.Sort{Column: column, Descending: descending})
+		}
+	}
+
+	return filters, sorts, nil
+}
+
+// sendBatchError answers the errors of a batch which can't start, be retried or be cancelled
+func (h *HTTPHandler) sendBatchError(resp http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrBatchNotFound):
+		_ = httpresponse.SendJSONResponse(resp, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, usecase.ErrInvalidBatch) || errors.Is(err, usecase.ErrTooManyBatches) || errors.Is(err, usecase.ErrBatchNotRetryable) ||
+		errors.Is(err, usecase.ErrBatchNotRunning):
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+	default:
+		_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, httpresponse.HTTPStatusInternalServerErrorMessage, nil)
+	}
+}
+
+// sendExecutionError answers 400 with the invalid fields when params were rejected, 500 otherwise
+func (h *HTTPHandler) sendExecutionError(resp http.ResponseWriter, err error) {
+	var validationErr *paramschema.ValidationError
+	if errors.As(err, &validationErr) {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, errInvalidParams, validationErr.Fields)
+		return
+	}
+
+	if errors.Is(err, usecase.ErrNotStreamable) || errors.Is(err, usecase.ErrNotPaginated) || errors.Is(err, paginator.ErrInvalidCursor) ||
+		errors.Is(err, usecase.ErrInvalidFilter) || errors.Is(err, usecase.ErrInvalidTransaction) || errors.Is(err, sqlqueryhelper.ErrUnboundParam) {
+		_ = httpresponse.SendJSONResponse(resp, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	_ = httpresponse.SendJSONResponse(resp, http.StatusInternalServerError, failedAPIMessage, nil)
+}
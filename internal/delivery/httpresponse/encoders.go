@@ -0,0 +1,232 @@
+package httpresponse
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonEncoder writes {"code":200,"message":message,"data":{"Rows":[...],"AffectedRows":n}}, row objects keep the column order
+type jsonEncoder struct {
+	w       *bufio.Writer
+	message string
+	keys    [][]byte
+	rows    int
+}
+
+func (e *jsonEncoder) writeHeader(columns []string) error {
+	keys, err := jsonKeys(columns)
+	if err != nil {
+		return err
+	}
+	e.keys = keys
+
+	message, err := json.Marshal(e.message)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.WriteString(`{"code":200,"message":` + string(message) + `,"data":{"Rows":[`)
+	return err
+}
+
+func (e *jsonEncoder) writeRow(values []any) error {
+	if e.rows > 0 {
+		e.w.WriteByte(',')
+	}
+	e.rows++
+
+	return writeJSONObject(e.w, e.keys, values)
+}
+
+func (e *jsonEncoder) writeFooter(affectedRows int64, failure string) error {
+	e.w.WriteString(`],"AffectedRows":` + strconv.FormatInt(affectedRows, 10))
+	if failure != "" {
+		failureJSON, err := json.Marshal(failure)
+		if err != nil {
+			return err
+		}
+		e.w.WriteString(`,"Error":` + string(failureJSON))
+	}
+
+	_, err := e.w.WriteString("}}")
+	return err
+}
+
+// ndjsonEncoder writes one row object per line
+type ndjsonEncoder struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func (e *ndjsonEncoder) writeHeader(columns []string) error {
+	keys, err := jsonKeys(columns)
+	e.keys = keys
+
+	return err
+}
+
+func (e *ndjsonEncoder) writeRow(values []any) error {
+	if err := writeJSONObject(e.w, e.keys, values); err != nil {
+		return err
+	}
+
+	return e.w.WriteByte('\n')
+}
+
+func (e *ndjsonEncoder) writeFooter(_ int64, failure string) error {
+	if failure == "" {
+		return nil
+	}
+
+	failureJSON, err := json.Marshal(failure)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.WriteString(`{"error":` + string(failureJSON) + "}\n")
+	return err
+}
+
+// csvEncoder writes a header line with the column names then one record per row, NULL is an empty field
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVEncoder(w *bufio.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) writeHeader(columns []string) error {
+	e.record = make([]string, len(columns))
+	return e.w.Write(columns)
+}
+
+func (e *csvEncoder) writeRow(values []any) error {
+	for idx, value := range values {
+		e.record[idx], _ = formatValue(value)
+	}
+
+	if err := e.w.Write(e.record); err != nil {
+		return err
+	}
+
+	// the csv writer has its own buffer, it is flushed into the response buffer at each row
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) writeFooter(int64, string) error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// xmlEncoder writes <result><row><field name="column">value</field>...</row>...<affected_rows>n</affected_rows></result>,
+// a NULL field has a null="true" attribute
+type xmlEncoder struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func (e *xmlEncoder) writeHeader(columns []string) error {
+	e.columns = make([]string, len(columns))
+	for idx, column := range columns {
+		e.columns[idx] = escapeXML(column)
+	}
+
+	_, err := e.w.WriteString(xml.Header + "<result>")
+	return err
+}
+
+func (e *xmlEncoder) writeRow(values []any) error {
+	e.w.WriteString("<row>")
+	for idx, value := range values {
+		text, isNull := formatValue(value)
+		if isNull {
+			e.w.WriteString(`<field name="` + e.columns[idx] + `" null="true"/>`)
+			continue
+		}
+		e.w.WriteString(`<field name="` + e.columns[idx] + `">` + escapeXML(text) + "</field>")
+	}
+
+	_, err := e.w.WriteString("</row>")
+	return err
+}
+
+func (e *xmlEncoder) writeFooter(affectedRows int64, failure string) error {
+	e.w.WriteString("<affected_rows>" + strconv.FormatInt(affectedRows, 10) + "</affected_rows>")
+	if failure != "" {
+		e.w.WriteString("<error>" + escapeXML(failure) + "</error>")
+	}
+
+	_, err := e.w.WriteString("</result>")
+	return err
+}
+
+func jsonKeys(columns []string) ([][]byte, error) {
+	keys := make([][]byte, len(columns))
+	for idx, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		keys[idx] = key
+	}
+
+	return keys, nil
+}
+
+func writeJSONObject(w *bufio.Writer, keys [][]byte, values []any) error {
+	w.WriteByte('{')
+	for idx, value := range values {
+		if idx > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(keys[idx])
+		w.WriteByte(':')
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		w.Write(encoded)
+	}
+
+	return w.WriteByte('}')
+}
+
+// formatValue returns the text of a value for the text formats, the same way it is encoded in json
+func formatValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, false
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), false
+	case time.Time:
+		return v.Format(time.RFC3339Nano), false
+	case bool:
+		return strconv.FormatBool(v), false
+	case int64:
+		return strconv.FormatInt(v, 10), false
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), false
+	default:
+		return fmt.Sprint(v), false
+	}
+}
+
+func escapeXML(text string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(text))
+
+	return escaped.String()
+}
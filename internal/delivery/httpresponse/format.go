@@ -0,0 +1,112 @@
+package httpresponse
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Result formats of a target
+const (
+	FormatJSON   string = "json"
+	FormatNDJSON string = "ndjson"
+	FormatCSV    string = "csv"
+	FormatXLSX   string = "xlsx"
+	FormatXML    string = "xml"
+)
+
+const (
+	NDJSONContentType string = "application/x-ndjson"
+	CSVContentType    string = "text/csv"
+	XLSXContentType   string = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	XMLContentType    string = "application/xml"
+)
+
+var ErrUnsupportedFormat error = errors.New("unsupported result format")
+
+var formatContentTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatNDJSON: NDJSONContentType,
+	FormatCSV:    CSVContentType,
+	FormatXLSX:   XLSXContentType,
+	FormatXML:    XMLContentType,
+}
+
+var mediaTypeFormats = map[string]string{
+	"application/json":     FormatJSON,
+	"application/x-ndjson": FormatNDJSON,
+	"application/ndjson":   FormatNDJSON,
+	"text/csv":             FormatCSV,
+	XLSXContentType:        FormatXLSX,
+	"application/xml":      FormatXML,
+	"text/xml":             FormatXML,
+}
+
+type acceptedMediaType struct {
+	mediaType string
+	quality   float64
+}
+
+// NegotiateFormat returns the result format asked by the format query parameter, or by the Accept header,
+// or defaultFormat. An Accept header preferring text/html, as sent by browsers, keeps defaultFormat.
+func NegotiateFormat(req *http.Request, defaultFormat string) (string, error) {
+	if format := req.URL.Query().Get("format"); format != "" {
+		if _, exists := formatContentTypes[format]; !exists {
+			return "", ErrUnsupportedFormat
+		}
+		return format, nil
+	}
+
+	if defaultFormat == "" {
+		defaultFormat = FormatJSON
+	}
+
+	for idx, accepted := range parseAccept(req.Header.Get("Accept")) {
+		if format, exists := mediaTypeFormats[accepted.mediaType]; exists {
+			return format, nil
+		}
+		if (idx == 0 && accepted.mediaType == "text/html") || strings.HasSuffix(accepted.mediaType, "/*") {
+			break
+		}
+	}
+
+	return defaultFormat, nil
+}
+
+// parseAccept returns the media types of an Accept header, from the most preferred
+func parseAccept(header string) []acceptedMediaType {
+	var accepted []acceptedMediaType
+
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if value, exists := params["q"]; exists {
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			accepted = append(accepted, acceptedMediaType{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	slices.SortStableFunc(accepted, func(a, b acceptedMediaType) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return accepted
+}
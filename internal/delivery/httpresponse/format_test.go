@@ -0,0 +1,44 @@
+package httpresponse_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/delivery/httpresponse"
+
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		url           string
+		accept        string
+		defaultFormat string
+		expected      string
+	}{
+		{"/t", "", "", httpresponse.FormatJSON},
+		{"/t", "", httpresponse.FormatCSV, httpresponse.FormatCSV},
+		{"/t?format=xlsx", "text/csv", httpresponse.FormatCSV, httpresponse.FormatXLSX},
+		{"/t", "text/csv", "", httpresponse.FormatCSV},
+		{"/t", "application/xml;q=0.5, application/x-ndjson", "", httpresponse.FormatNDJSON},
+		{"/t", "image/png, " + httpresponse.XLSXContentType + ";q=0.8", "", httpresponse.FormatXLSX},
+		{"/t", "*/*", httpresponse.FormatXML, httpresponse.FormatXML},
+		{"/t", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", httpresponse.FormatJSON},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest("GET", testCase.url, nil)
+		req.Header.Set("Accept", testCase.accept)
+
+		got, err := httpresponse.NegotiateFormat(req, testCase.defaultFormat)
+		if err != nil || got != testCase.expected {
+			t.Errorf("NegotiateFormat(%s, %q, %q) = %s, %v, want %s", testCase.url, testCase.accept, testCase.defaultFormat, got, err, testCase.expected)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/t?format=pdf", nil)
+	if _, err := httpresponse.NegotiateFormat(req, ""); !errors.Is(err, httpresponse.ErrUnsupportedFormat) {
+		t.Errorf("got error %v, want ErrUnsupportedFormat", err)
+	}
+}
@@ -0,0 +1,130 @@
+package httpresponse
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	// StreamErrorTrailer carries the failure of a stream interrupted after its first row
+	StreamErrorTrailer string = "X-Stream-Error"
+
+	streamBufferSize    int           = 32 << 10
+	streamFlushRows     int           = 1000
+	streamFlushInterval time.Duration = time.Second
+)
+
+// rowEncoder writes the rows of a result set in a format
+type rowEncoder interface {
+	writeHeader(columns []string) error
+	writeRow(values []any) error
+	writeFooter(affectedRows int64, failure string) error
+}
+
+// StreamWriter writes the rows of a streamed query to the response as they are read, in a result format.
+// Nothing is written before the columns, so a failure before the first row can still be answered normally.
+type StreamWriter struct {
+	resp       http.ResponseWriter
+	buf        *bufio.Writer
+	controller *http.ResponseController
+	format     string
+	filename   string
+	encoder    rowEncoder
+	started    bool
+	rows       int
+	lastFlush  time.Time
+}
+
+// NewStreamWriter streams the rows in format. The json format keeps the HTTPResp envelope with message,
+// csv and xlsx responses are attachments named after filename.
+func NewStreamWriter(resp http.ResponseWriter, format string, message string, filename string) *StreamWriter {
+	buf := bufio.NewWriterSize(resp, streamBufferSize)
+
+	var encoder rowEncoder
+	switch format {
+	case FormatNDJSON:
+		encoder = &ndjsonEncoder{w: buf}
+	case FormatCSV:
+		encoder = newCSVEncoder(buf)
+	case FormatXLSX:
+		encoder = newXLSXEncoder(buf)
+	case FormatXML:
+		encoder = &xmlEncoder{w: buf}
+	default:
+		format = FormatJSON
+		encoder = &jsonEncoder{w: buf, message: message}
+	}
+
+	return &StreamWriter{
+		resp:       resp,
+		buf:        buf,
+		controller: http.NewResponseController(resp),
+		format:     format,
+		filename:   filename,
+		encoder:    encoder,
+	}
+}
+
+// Started reports whether the response status and headers are sent
+func (w *StreamWriter) Started() bool {
+	return w.started
+}
+
+func (w *StreamWriter) WriteColumns(columns []string) error {
+	header := w.resp.Header()
+	header.Set("Content-Type", formatContentTypes[w.format])
+	header.Set("Trailer", StreamErrorTrailer)
+	if (w.format == FormatCSV || w.format == FormatXLSX) && w.filename != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": w.filename + "." + w.format}))
+	}
+
+	w.resp.WriteHeader(http.StatusOK)
+	w.started = true
+	w.lastFlush = time.Now()
+
+	return w.encoder.writeHeader(columns)
+}
+
+func (w *StreamWriter) WriteRow(values []any) error {
+	if err := w.encoder.writeRow(values); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%streamFlushRows == 0 || time.Since(w.lastFlush) >= streamFlushInterval {
+		return w.flush()
+	}
+
+	return nil
+}
+
+// Close ends the response. A non empty failure, for an error after the first row, is sent in the
+// X-Stream-Error trailer, and also in the Error field of the json data, as a last {"error":failure}
+// NDJSON line or as an error element in XML.
+func (w *StreamWriter) Close(affectedRows int64, failure string) error {
+	if failure != "" {
+		w.resp.Header().Set(StreamErrorTrailer, failure)
+	}
+
+	if err := w.encoder.writeFooter(affectedRows, failure); err != nil {
+		return err
+	}
+
+	return w.flush()
+}
+
+func (w *StreamWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	w.lastFlush = time.Now()
+
+	if err := w.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
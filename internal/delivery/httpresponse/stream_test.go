@@ -0,0 +1,207 @@
+package httpresponse_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/delivery/httpresponse"
+
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamWriter_JSON(t *testing.T) {
+	t.Parallel()
+
+	recorder := httptest.NewRecorder()
+	writer := httpresponse.NewStreamWriter(recorder, httpresponse.FormatJSON, "done", "")
+
+	if err := writer.WriteColumns([]string{"id", "name"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]any{{int64(1), "a"}, {int64(2), nil}} {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(2, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Rows         []map[string]any
+			AffectedRows int64
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json %q: %v", recorder.Body.String(), err)
+	}
+
+	if body.Code != 200 || body.Message != "done" || body.Data.AffectedRows != 2 || len(body.Data.Rows) != 2 {
+		t.Errorf("unexpected body %s", recorder.Body.String())
+	}
+	if body.Data.Rows[1]["name"] != nil || body.Data.Rows[0]["name"] != "a" {
+		t.Errorf("unexpected rows %v", body.Data.Rows)
+	}
+}
+
+func TestStreamWriter_NDJSONWithFailure(t *testing.T) {
+	t.Parallel()
+
+	recorder := httptest.NewRecorder()
+	writer := httpresponse.NewStreamWriter(recorder, httpresponse.FormatNDJSON, "", "")
+
+	if writer.Started() {
+		t.Fatal("writer should not be started before the columns")
+	}
+	if err := writer.WriteColumns([]string{"id"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRow([]any{int64(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(1, "failed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := recorder.Header().Get("Content-Type"); got != httpresponse.NDJSONContentType {
+		t.Errorf("got content type %s", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"id":1}` || lines[1] != `{"error":"failed"}` {
+		t.Errorf("got lines %q", lines)
+	}
+
+	if got := recorder.Result().Trailer.Get(httpresponse.StreamErrorTrailer); got != "failed" {
+		t.Errorf("got trailer %q, want failed", got)
+	}
+}
+
+func writeRows(t *testing.T, writer *httpresponse.StreamWriter, columns []string, rows ...[]any) {
+	t.Helper()
+
+	if err := writer.WriteColumns(columns); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(int64(len(rows)), ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamWriter_CSV(t *testing.T) {
+	t.Parallel()
+
+	recorder := httptest.NewRecorder()
+	writer := httpresponse.NewStreamWriter(recorder, httpresponse.FormatCSV, "", "students")
+	writeRows(t, writer, []string{"name", "id", "age"},
+		[]any{"Doe, John", int64(1), nil},
+		[]any{`say "hi"`, int64(2), 15.5},
+	)
+
+	want := "name,id,age\n\"Doe, John\",1,\n\"say \"\"hi\"\"\",2,15.5\n"
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := recorder.Header().Get("Content-Disposition"); got != `attachment; filename=students.csv` {
+		t.Errorf("got content disposition %q", got)
+	}
+}
+
+func TestStreamWriter_XML(t *testing.T) {
+	t.Parallel()
+
+	recorder := httptest.NewRecorder()
+	writer := httpresponse.NewStreamWriter(recorder, httpresponse.FormatXML, "", "")
+	writeRows(t, writer, []string{"id", "name"},
+		[]any{int64(1), "<a & b>"},
+		[]any{int64(2), nil},
+	)
+
+	var result struct {
+		Rows []struct {
+			Fields []struct {
+				Name  string `xml:"name,attr"`
+				Null  bool   `xml:"null,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"field"`
+		} `xml:"row"`
+		AffectedRows int `xml:"affected_rows"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid xml %q: %v", recorder.Body.String(), err)
+	}
+
+	if len(result.Rows) != 2 || result.AffectedRows != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if field := result.Rows[0].Fields[1]; field.Name != "name" || field.Value != "<a & b>" {
+		t.Errorf("unexpected field %+v", field)
+	}
+	if field := result.Rows[1].Fields[1]; !field.Null {
+		t.Errorf("field %+v should be null", field)
+	}
+}
+
+func TestStreamWriter_XLSX(t *testing.T) {
+	t.Parallel()
+
+	recorder := httptest.NewRecorder()
+	writer := httpresponse.NewStreamWriter(recorder, httpresponse.FormatXLSX, "", "students")
+	writeRows(t, writer, []string{"id", "name", "active"},
+		[]any{int64(1), "Alice", true},
+		[]any{int64(2), nil, false},
+	)
+
+	body := recorder.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("invalid xlsx archive: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if _, exists := files[name]; !exists {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, cell := range []string{
+		`<c r="B1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="C2" t="b"><v>1</v></c>`,
+		`<row r="3"><c r="A3"><v>2</v></c><c r="C3" t="b"><v>0</v></c></row>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("sheet %s should contain %s", sheet, cell)
+		}
+	}
+	if err := xml.Unmarshal([]byte(sheet), new(any)); err != nil {
+		t.Errorf("invalid sheet xml: %v", err)
+	}
+}
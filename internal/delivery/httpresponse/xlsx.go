@@ -0,0 +1,139 @@
+package httpresponse
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// The minimal parts of a workbook with a single sheet, cells hold inline strings so no shared strings table is needed
+const (
+	xlsxContentTypes string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="result" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetHeader string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter string = `</sheetData></worksheet>`
+)
+
+// xlsxEncoder writes a workbook whose sheet is streamed into the zip archive row by row.
+// The first row holds the column names, numbers and booleans are typed cells, NULL is an empty cell.
+type xlsxEncoder struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	buf     []byte
+	row     int
+	refs    []string
+}
+
+func newXLSXEncoder(w *bufio.Writer) *xlsxEncoder {
+	return &xlsxEncoder{archive: zip.NewWriter(w)}
+}
+
+func (e *xlsxEncoder) writeHeader(columns []string) error {
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		w, err := e.archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := e.archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = sheet
+
+	e.refs = make([]string, len(columns))
+	for idx := range columns {
+		e.refs[idx] = xlsxColumnName(idx)
+	}
+
+	if _, err := io.WriteString(e.sheet, xlsxSheetHeader); err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	for idx, column := range columns {
+		values[idx] = column
+	}
+
+	return e.writeRow(values)
+}
+
+func (e *xlsxEncoder) writeRow(values []any) error {
+	e.row++
+	rowRef := strconv.Itoa(e.row)
+
+	e.buf = append(e.buf[:0], `<row r="`+rowRef+`">`...)
+	for idx, value := range values {
+		text, isNull := formatValue(value)
+		if isNull {
+			continue
+		}
+
+		ref := e.refs[idx] + rowRef
+		switch v := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			e.buf = append(e.buf, `<c r="`+ref+`"><v>`+text+`</v></c>`...)
+		case bool:
+			bit := "0"
+			if v {
+				bit = "1"
+			}
+			e.buf = append(e.buf, `<c r="`+ref+`" t="b"><v>`+bit+`</v></c>`...)
+		default:
+			e.buf = append(e.buf, `<c r="`+ref+`" t="inlineStr"><is><t xml:space="preserve">`+escapeXML(text)+`</t></is></c>`...)
+		}
+	}
+	e.buf = append(e.buf, `</row>`...)
+
+	_, err := e.sheet.Write(e.buf)
+	return err
+}
+
+// writeFooter closes the sheet and the archive, a failure is only reported by the trailer
+func (e *xlsxEncoder) writeFooter(int64, string) error {
+	if _, err := io.WriteString(e.sheet, xlsxSheetFooter); err != nil {
+		return err
+	}
+
+	return e.archive.Close()
+}
+
+// xlsxColumnName returns the letters of a column index starting at 0 : A, B, ..., Z, AA, AB, ...
+func xlsxColumnName(idx int) string {
+	name := ""
+	for idx >= 0 {
+		name = string(rune('A'+idx%26)) + name
+		idx = idx/26 - 1
+	}
+
+	return name
+}
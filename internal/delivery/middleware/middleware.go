@@ -12,7 +12,7 @@ import (
 )
 
 type IAuthMiddleware interface {
-	Authenticate(next http.Handler, config *config.Config) http.Handler
+	Authenticate(next http.Handler, store *config.Store) http.Handler
 }
 
 type AuthMiddleware struct {
@@ -23,9 +23,10 @@ func NewAuthMiddleware(logger zerolog.Logger) *AuthMiddleware {
 	return &AuthMiddleware{logger}
 }
 
-func (a *AuthMiddleware) Authenticate(next http.Handler, config *config.Config) http.Handler {
+func (a *AuthMiddleware) Authenticate(next http.Handler, store *config.Store) http.Handler {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		auth := req.Header.Get("Authorization")
+		config := store.Get()
 
 		if config.ApiGatewaySQL.Enabled && !strings.HasPrefix(req.RequestURI, "/swagger/") && !strings.HasPrefix(req.RequestURI, "/healthz") {
 			if auth == "" {
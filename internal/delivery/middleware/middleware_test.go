@@ -68,7 +68,7 @@ func triggerTest(t *testing.T, statusCode int, credential string) {
 	}).Methods("GET")
 	router.Use(func(next http.Handler) http.Handler {
 		authMiddleware := middleware.NewAuthMiddleware(logger)
-		return authMiddleware.Authenticate(next, configLoaded)
+		return authMiddleware.Authenticate(next, config.NewStore(configLoaded, ""))
 	})
 	router.ServeHTTP(rr, req)
 
@@ -1,10 +1,25 @@
 package domain
 
+// Status of a batch
+const (
+	BatchRunning   string = "running"
+	BatchCompleted string = "completed"
+	// BatchFailed is a batch stopped before its end, such as a batch whose process stopped
+	BatchFailed string = "failed"
+	// BatchCancelled is a batch stopped on request, after its last line
+	BatchCancelled string = "cancelled"
+)
+
 type BatchStat struct {
-	ID         string  `json:"id" gorm:"primaryKey"`
-	TargetName string  `json:"target"`
-	Completed  bool    `json:"completed"`
-	CreatedAt  int64   `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt  int64   `json:"updated_at" gorm:"autoUpdateTime"`
-	Blocks     []Block `json:"blocks" gorm:"foreignKey:BatchStatID"`
+	ID             string  `json:"id" gorm:"primaryKey"`
+	TargetName     string  `json:"target" gorm:"index"`
+	DataSourceName string  `json:"data_source" gorm:"index"`
+	Completed      bool    `json:"completed"`
+	Status         string  `json:"status"`
+	HeartbeatAt    int64   `json:"heartbeat_at"`
+	LastLine       int     `json:"last_line"`
+	Retries        int     `json:"retries"`
+	CreatedAt      int64   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      int64   `json:"updated_at" gorm:"autoUpdateTime"`
+	Blocks         []Block `json:"blocks" gorm:"foreignKey:BatchStatID"`
 }
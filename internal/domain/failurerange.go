@@ -2,14 +2,19 @@ package domain
 
 import "github.com/willbrid/api-gateway-sql/pkg/uuid"
 
+// FailureRange is a range of failed records of a block, numbered by record of the csv file from 1 without its
+// header, EndLine included. A quoted field holding line breaks makes a record span several lines of the file.
+// Ranges recorded before were numbered from 0 in their block, EndLine excluded, FileLines tells the ranges
+// numbered by record of the file.
 type FailureRange struct {
 	ID        string `json:"id" gorm:"primaryKey"`
 	StartLine int    `json:"start_line"`
 	EndLine   int    `json:"end_line"`
+	FileLines bool   `json:"-" gorm:"not null;default:false"`
 	CreatedAt int64  `json:"created_at" gorm:"autoCreateTime"`
 	BlockID   string
 }
 
 func NewFailureRange(startLine, endLine int) *FailureRange {
-	return &FailureRange{ID: uuid.GenerateUID(), StartLine: startLine, EndLine: endLine}
+	return &FailureRange{ID: uuid.GenerateUID(), StartLine: startLine, EndLine: endLine, FileLines: true}
 }
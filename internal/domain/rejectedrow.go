@@ -0,0 +1,18 @@
+package domain
+
+import "github.com/willbrid/api-gateway-sql/pkg/uuid"
+
+// RejectedRow is a csv line of a batch whose execution failed on its own, with the error of the database
+type RejectedRow struct {
+	ID          string `json:"id" gorm:"primaryKey"`
+	Line        int    `json:"line" gorm:"index"`
+	Values      string `json:"values"`
+	Error       string `json:"error"`
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime"`
+	BlockID     string `json:"block_id" gorm:"index"`
+	BatchStatID string `json:"batch_stat_id" gorm:"index"`
+}
+
+func NewRejectedRow(line int, values string, message string) *RejectedRow {
+	return &RejectedRow{ID: uuid.GenerateUID(), Line: line, Values: values, Error: message}
+}
@@ -0,0 +1,13 @@
+package domain
+
+// SchemaMigration is a migration applied on a datasource, stored in the datasource itself
+type SchemaMigration struct {
+	Version   int64  `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string `json:"name" gorm:"size:255"`
+	Checksum  string `json:"checksum" gorm:"size:64"`
+	AppliedAt int64  `json:"applied_at" gorm:"autoCreateTime"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "api_gateway_sql_migrations"
+}
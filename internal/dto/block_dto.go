@@ -12,3 +12,24 @@ type BlockDataInput struct {
 	TGInput *config.Target
 	DBInput *config.Database
 }
+
+// BatchRun is a batch registered as running, whose staged csv file is read with the target
+type BatchRun struct {
+	BSInput *domain.BatchStat
+	TGInput *config.Target
+	DBInput *config.Database
+}
+
+// BatchRetry is a completed batch whose failed lines are run again with SqlQuery
+type BatchRetry struct {
+	BSInput  *domain.BatchStat
+	TGInput  *config.Target
+	DBInput  *config.Database
+	SqlQuery string
+}
+
+// BatchRejects are the rejected rows of a batch, whose values are the Fields of its target
+type BatchRejects struct {
+	Fields []string
+	Rows   []*domain.RejectedRow
+}
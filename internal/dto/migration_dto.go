@@ -0,0 +1,25 @@
+package dto
+
+// Migration states reported by MigrationStatus
+const (
+	MigrationApplied  string = "applied"
+	MigrationPending  string = "pending"
+	MigrationModified string = "modified"
+	MigrationUnknown  string = "unknown"
+)
+
+type MigrationInput struct {
+	Datasource string
+	// Files holds uploaded migration files by filename, the datasource migrations_dir is used when empty
+	Files   map[string]string
+	Version int64
+	Steps   int
+}
+
+type MigrationStatus struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Checksum  string `json:"checksum"`
+	AppliedAt int64  `json:"applied_at,omitempty"`
+}
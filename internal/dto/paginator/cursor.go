@@ -0,0 +1,52 @@
+package paginator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor error = errors.New("invalid page cursor")
+
+// EncodeCursor returns the opaque token of a cursor column value : the base64url of its json encoding
+func EncodeCursor(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// DecodeCursor returns the value of a cursor token, numbers are decoded as int64 when they are integers
+func DecodeCursor(token string) (any, error) {
+	encoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		if integer, err := v.Int64(); err == nil {
+			return integer, nil
+		}
+		float, err := v.Float64()
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return float, nil
+	case string, bool:
+		return v, nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
@@ -5,10 +5,12 @@ const defaultPageSize int = 10
 type PageRequest struct {
 	PageNum  int
 	PageSize int
+	// Cursor is the token of the last row of the previous keyset page, empty for the first page
+	Cursor string
 }
 
 func NewPageRequest(pageNum, pageSize int) *PageRequest {
-	return &PageRequest{pageNum, pageSize}
+	return &PageRequest{PageNum: pageNum, PageSize: pageSize}
 }
 
 func (p PageRequest) Offset() int {
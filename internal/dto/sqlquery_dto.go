@@ -1,15 +1,85 @@
 package dto
 
-import "mime/multipart"
+import (
+	"fmt"
+	"mime/multipart"
+)
 
 type SQLQueryOutput struct {
 	Rows         []map[string]any
 	AffectedRows int64
+	Steps        []SQLStepOutput    `json:",omitempty"`
+	ResultSets   [][]map[string]any `json:",omitempty"`
+	OutParams    map[string]any     `json:",omitempty"`
+}
+
+// ProcedureCall calls a stored procedure with the params in their declaration order
+type ProcedureCall struct {
+	Name   string
+	Params []ProcedureParam
+}
+
+// ProcedureParam is an argument of a procedure, Type is the param type declared by the target
+type ProcedureParam struct {
+	Name      string
+	Type      string
+	Direction string
+}
+
+// SQLStep is a statement of a target running several statements in a single transaction.
+// Params are bound to this statement only, along with the params of the call.
+type SQLStep struct {
+	Name   string
+	Query  string
+	Mode   string
+	Params map[string]any
+}
+
+// SQLStepOutput holds the rows, or the affected rows count, of a statement
+type SQLStepOutput struct {
+	Name         string
+	Rows         []map[string]any
+	AffectedRows int64
 }
 
 type SQLQueryInput struct {
 	TargetName string
 	PostParams map[string]any
+	Filters    []Filter
+	Sorts      []Sort
+}
+
+// Filter keeps the rows whose column compares to the value, Operator is one of eq, ne, lt, lte, gt, gte and like
+type Filter struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+// Sort orders the rows by a column
+type Sort struct {
+	Column     string
+	Descending bool
+}
+
+// StepError tells which statement of a transaction failed, Index is its position in the statements
+type StepError struct {
+	Index int
+	Name  string
+	Err   error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s: %v", e.Name, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// SQLTransactionInput runs targets of a same datasource in order, in a single transaction
+type SQLTransactionInput struct {
+	Steps []SQLQueryInput
 }
 
 type SQLBatchQueryInput struct {
@@ -17,7 +87,20 @@ type SQLBatchQueryInput struct {
 	File       multipart.File
 }
 
+// SQLBatchRetryInput runs again the failed lines of a batch, with SqlQuery instead of the sql of its target
+// when it is set
+type SQLBatchRetryInput struct {
+	BatchStatID string
+	SqlQuery    string
+}
+
 type SQLInitDatabaseInput struct {
 	Datasource     string
 	SQLFileContent string
 }
+
+// RowWriter receives the rows of a streamed select query, the columns are written before the first row
+type RowWriter interface {
+	WriteColumns(columns []string) error
+	WriteRow(values []any) error
+}
@@ -0,0 +1,192 @@
+package columntype
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Json types of the column values
+const (
+	String string = "string"
+	Number string = "number"
+	Time   string = "time"
+	Date   string = "date"
+	UUID   string = "uuid"
+	Base64 string = "base64"
+	// Raw keeps the value returned by the driver
+	Raw string = "raw"
+)
+
+const dateLayout string = "2006-01-02"
+
+// databaseTypes maps the database type names reported by the drivers to the json type of their values
+var databaseTypes = map[string]string{
+	"DECIMAL":          String,
+	"NUMERIC":          String,
+	"MONEY":            String,
+	"SMALLMONEY":       String,
+	"DATE":             Date,
+	"DATETIME":         Time,
+	"DATETIME2":        Time,
+	"SMALLDATETIME":    Time,
+	"DATETIMEOFFSET":   Time,
+	"TIMESTAMP":        Time,
+	"TIMESTAMPTZ":      Time,
+	"UUID":             UUID,
+	"UNIQUEIDENTIFIER": UUID,
+	"BYTEA":            Base64,
+	"BLOB":             Base64,
+	"TINYBLOB":         Base64,
+	"MEDIUMBLOB":       Base64,
+	"LONGBLOB":         Base64,
+	"BINARY":           Base64,
+	"VARBINARY":        Base64,
+	"IMAGE":            Base64,
+}
+
+// textTimeLayouts are the layouts of the times returned as text, such as mysql times without parseTime
+var textTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", dateLayout}
+
+// Of returns the json type of the values of a database type, such as DECIMAL(10,2), or Raw for the types whose
+// driver values are kept
+func Of(databaseType string) string {
+	name, _, _ := strings.Cut(strings.ToUpper(strings.TrimSpace(databaseType)), "(")
+	if jsonType, exists := databaseTypes[strings.TrimSpace(name)]; exists {
+		return jsonType
+	}
+
+	return Raw
+}
+
+// Normalize converts a driver value to a json type : decimals become strings to keep their precision, times
+// RFC3339 strings, dates 2006-01-02 strings, uuids canonical strings and binary values base64 strings. Sqlserver
+// uniqueidentifier bytes are reordered. A value which can't be converted is kept, NULL stays nil.
+func Normalize(dialect string, jsonType string, value any) any {
+	if value == nil {
+		return nil
+	}
+
+	switch jsonType {
+	case String:
+		return toString(value)
+	case Number:
+		return toNumber(value)
+	case Time:
+		return formatTime(value, time.RFC3339Nano)
+	case Date:
+		return formatTime(value, dateLayout)
+	case UUID:
+		return toUUID(dialect, value)
+	case Base64:
+		if bytes, ok := value.([]byte); ok {
+			return base64.StdEncoding.EncodeToString(bytes)
+		}
+		if text, ok := value.(string); ok {
+			return base64.StdEncoding.EncodeToString([]byte(text))
+		}
+	}
+
+	return value
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toNumber returns a json number, keeping the digits of a decimal
+func toNumber(value any) any {
+	switch v := value.(type) {
+	case string, []byte:
+		text := strings.TrimSpace(toString(v))
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return toString(v)
+		}
+		return json.Number(text)
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	default:
+		return value
+	}
+}
+
+func formatTime(value any, layout string) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout)
+	case string, []byte:
+		text := toString(v)
+		for _, textLayout := range textTimeLayouts {
+			if parsed, err := time.Parse(textLayout, text); err == nil {
+				return parsed.Format(layout)
+			}
+		}
+		return text
+	default:
+		return value
+	}
+}
+
+// toUUID formats a uuid returned as 16 bytes or as text. Sqlserver sends the first three groups of a
+// uniqueidentifier in little endian order.
+func toUUID(dialect string, value any) any {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case [16]byte:
+		bytes = v[:]
+	case string:
+		if parsed, err := uuid.Parse(v); err == nil {
+			return parsed.String()
+		}
+		return v
+	default:
+		return value
+	}
+
+	if len(bytes) != 16 {
+		if parsed, err := uuid.ParseBytes(bytes); err == nil {
+			return parsed.String()
+		}
+		return string(bytes)
+	}
+
+	ordered := make([]byte, 16)
+	copy(ordered, bytes)
+	if dialect == sqllexer.SQLServerDialect {
+		ordered[0], ordered[1], ordered[2], ordered[3] = bytes[3], bytes[2], bytes[1], bytes[0]
+		ordered[4], ordered[5] = bytes[5], bytes[4]
+		ordered[6], ordered[7] = bytes[7], bytes[6]
+	}
+
+	parsed, err := uuid.FromBytes(ordered)
+	if err != nil {
+		return value
+	}
+
+	return parsed.String()
+}
@@ -0,0 +1,68 @@
+package columntype_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/columntype"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOf(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"DECIMAL":          columntype.String,
+		"numeric(10,2)":    columntype.String,
+		"TIMESTAMPTZ":      columntype.Time,
+		"DATE":             columntype.Date,
+		"UNIQUEIDENTIFIER": columntype.UUID,
+		"BYTEA":            columntype.Base64,
+		"VARCHAR":          columntype.Raw,
+		"":                 columntype.Raw,
+	}
+
+	for databaseType, expected := range testCases {
+		if got := columntype.Of(databaseType); got != expected {
+			t.Errorf("Of(%q) = %q, want %q", databaseType, got, expected)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	paris := time.FixedZone("CET", 3600)
+	sqlserverUUID := []byte{0x10, 0xc1, 0x7a, 0xf4, 0xcc, 0x58, 0x72, 0x43, 0xa5, 0x67, 0x0e, 0x02, 0xb2, 0xc3, 0xd4, 0x79}
+
+	testCases := []struct {
+		dialect  string
+		jsonType string
+		value    any
+		expected any
+	}{
+		{sqllexer.MySQLDialect, columntype.String, []byte("12345678901234567890.0123456789"), "12345678901234567890.0123456789"},
+		{sqllexer.SQLiteDialect, columntype.String, 1.5, "1.5"},
+		{sqllexer.PostgresDialect, columntype.String, nil, nil},
+		{sqllexer.PostgresDialect, columntype.Number, "12.50", json.Number("12.50")},
+		{sqllexer.PostgresDialect, columntype.Number, "NaN?", "NaN?"},
+		{sqllexer.PostgresDialect, columntype.Time, time.Date(2024, 1, 2, 3, 4, 5, 0, paris), "2024-01-02T03:04:05+01:00"},
+		{sqllexer.MySQLDialect, columntype.Time, []byte("2024-01-02 03:04:05.5"), "2024-01-02T03:04:05.5Z"},
+		{sqllexer.MySQLDialect, columntype.Date, []byte("2024-01-02"), "2024-01-02"},
+		{sqllexer.PostgresDialect, columntype.Date, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "2024-01-02"},
+		{sqllexer.SQLServerDialect, columntype.UUID, sqlserverUUID, "f47ac110-58cc-4372-a567-0e02b2c3d479"},
+		{sqllexer.PostgresDialect, columntype.UUID, "F47AC10B-58CC-4372-A567-0E02B2C3D479", "f47ac10b-58cc-4372-a567-0e02b2c3d479"},
+		{sqllexer.PostgresDialect, columntype.UUID, [16]byte{0xf4, 0x7a, 0xc1, 0x10}, "f47ac110-0000-0000-0000-000000000000"},
+		{sqllexer.PostgresDialect, columntype.Base64, []byte{0, 1, 2, 255}, "AAEC/w=="},
+		{sqllexer.MySQLDialect, columntype.Raw, []byte("raw"), []byte("raw")},
+	}
+
+	for _, testCase := range testCases {
+		got := columntype.Normalize(testCase.dialect, testCase.jsonType, testCase.value)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("Normalize(%s, %s, %v) = %#v, want %#v", testCase.dialect, testCase.jsonType, testCase.value, got, testCase.expected)
+		}
+	}
+}
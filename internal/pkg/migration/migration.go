@@ -0,0 +1,183 @@
+package migration
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMigration error = errors.New("invalid migration")
+	ErrChecksumMismatch error = errors.New("migration modified since applied")
+	ErrIrreversible     error = errors.New("migration has no down script")
+)
+
+var filenameRegex = regexp.MustCompile(`^(\d+)_([\w\-]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered pair of up and down scripts, the checksum is computed on the up script
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Parse builds the migrations from NNN_name.up.sql and NNN_name.down.sql files, indexed by filename.
+// Migrations are sorted by version, each version must have a single up script.
+func Parse(files map[string]string) ([]Migration, error) {
+	byVersion := make(map[int64]*Migration, len(files))
+
+	for filename, content := range files {
+		matches := filenameRegex.FindStringSubmatch(filepath.Base(filename))
+		if matches == nil {
+			return nil, fmt.Errorf("%w: %s doesn't match NNN_name.up.sql or NNN_name.down.sql", ErrInvalidMigration, filename)
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s has an invalid version", ErrInvalidMigration, filename)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("%w: version %d is used by %s and %s", ErrInvalidMigration, version, migration.Name, matches[2])
+		}
+
+		script := &migration.Up
+		if matches[3] == "down" {
+			script = &migration.Down
+		}
+		if *script != "" {
+			return nil, fmt.Errorf("%w: %s is duplicated", ErrInvalidMigration, filename)
+		}
+		*script = content
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("%w: version %d has no up script", ErrInvalidMigration, migration.Version)
+		}
+		migration.Checksum = Checksum(migration.Up)
+		migrations = append(migrations, *migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// LoadDir parses the .sql files of a directory, other files are ignored
+func LoadDir(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = string(content)
+	}
+
+	return Parse(files)
+}
+
+// Checksum returns the sha256 hex digest of a script
+func Checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Pending returns the migrations to apply, in order, up to the target version (0 for all).
+// applied maps the applied versions to their checksum, an applied migration whose script
+// changed or a pending migration older than the last applied one is an error.
+func Pending(migrations []Migration, applied map[int64]string, target int64) ([]Migration, error) {
+	var lastApplied int64
+	for version := range applied {
+		lastApplied = max(lastApplied, version)
+	}
+
+	pending := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		checksum, isApplied := applied[migration.Version]
+		switch {
+		case isApplied && checksum != migration.Checksum:
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		case isApplied:
+			continue
+		case target > 0 && migration.Version > target:
+			continue
+		case migration.Version < lastApplied:
+			return nil, fmt.Errorf("%w: %d_%s is older than the last applied version %d", ErrInvalidMigration, migration.Version, migration.Name, lastApplied)
+		}
+
+		pending = append(pending, migration)
+	}
+
+	return pending, nil
+}
+
+// Rollback returns the last steps applied migrations to revert, from the newest.
+// Each of them must be known, unchanged and have a down script.
+func Rollback(migrations []Migration, applied map[int64]string, steps int) ([]Migration, error) {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions)
+
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	steps = min(max(steps, 0), len(versions))
+	rollback := make([]Migration, 0, steps)
+	for _, version := range versions[:steps] {
+		migration, exists := byVersion[version]
+		switch {
+		case !exists:
+			return nil, fmt.Errorf("%w: applied version %d is not in the migrations", ErrInvalidMigration, version)
+		case applied[version] != migration.Checksum:
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		case strings.TrimSpace(migration.Down) == "":
+			return nil, fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+		}
+
+		rollback = append(rollback, migration)
+	}
+
+	return rollback, nil
+}
+
+// Transactional reports whether the DDL statements of a dialect can be rolled back.
+// Mysql commits implicitly each DDL statement.
+func Transactional(dialect string) bool {
+	return dialect != sqllexer.MySQLDialect
+}
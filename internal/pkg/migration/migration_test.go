@@ -0,0 +1,143 @@
+package migration_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/migration"
+
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func versions(migrations []migration.Migration) []int64 {
+	result := make([]int64, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, m.Version)
+	}
+
+	return result
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	migrations, err := migration.Parse(map[string]string{
+		"002_add_age.up.sql":          "alter table student add age int",
+		"001_create_student.up.sql":   "create table student (id int)",
+		"001_create_student.down.sql": "drop table student",
+		"010_create_teacher.up.sql":   "create table teacher (id int)",
+		"010_create_teacher.down.sql": "drop table teacher",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := versions(migrations); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 10 {
+		t.Fatalf("got versions %v, want [1 2 10]", got)
+	}
+	if migrations[0].Name != "create_student" || migrations[0].Down != "drop table student" {
+		t.Errorf("unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[1].Checksum != migration.Checksum("alter table student add age int") {
+		t.Errorf("unexpected checksum %s", migrations[1].Checksum)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []map[string]string{
+		{"create_student.sql": "create table student (id int)"},
+		{"001_create_student.down.sql": "drop table student"},
+		{"001_create_student.up.sql": "select 1", "001_create_teacher.up.sql": "select 1"},
+	}
+
+	for _, files := range testCases {
+		if _, err := migration.Parse(files); !errors.Is(err, migration.ErrInvalidMigration) {
+			t.Errorf("Parse(%v) error = %v, want ErrInvalidMigration", files, err)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for filename, content := range map[string]string{
+		"001_init.up.sql":   "create table student (id int)",
+		"001_init.down.sql": "drop table student",
+		"README.md":         "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, filename), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrations, err := migration.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].Name != "init" {
+		t.Errorf("got %+v, want the init migration", migrations)
+	}
+}
+
+func TestPending(t *testing.T) {
+	t.Parallel()
+
+	migrations, err := migration.Parse(map[string]string{
+		"001_a.up.sql": "select 1",
+		"002_b.up.sql": "select 2",
+		"003_c.up.sql": "select 3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := map[int64]string{1: migration.Checksum("select 1")}
+
+	pending, err := migration.Pending(migrations, applied, 0)
+	if err != nil || len(pending) != 2 || pending[0].Version != 2 {
+		t.Errorf("got %v, %v, want versions 2 and 3", versions(pending), err)
+	}
+
+	pending, err = migration.Pending(migrations, applied, 2)
+	if err != nil || len(pending) != 1 || pending[0].Version != 2 {
+		t.Errorf("got %v, %v, want version 2", versions(pending), err)
+	}
+
+	if _, err := migration.Pending(migrations, map[int64]string{1: "edited"}, 0); !errors.Is(err, migration.ErrChecksumMismatch) {
+		t.Errorf("got error %v, want ErrChecksumMismatch", err)
+	}
+
+	if _, err := migration.Pending(migrations, map[int64]string{3: migration.Checksum("select 3")}, 0); !errors.Is(err, migration.ErrInvalidMigration) {
+		t.Errorf("got error %v, want ErrInvalidMigration for an out of order migration", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	migrations, err := migration.Parse(map[string]string{
+		"001_a.up.sql":   "select 1",
+		"001_a.down.sql": "select -1",
+		"002_b.up.sql":   "select 2",
+		"002_b.down.sql": "select -2",
+		"003_c.up.sql":   "select 3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := map[int64]string{1: migration.Checksum("select 1"), 2: migration.Checksum("select 2")}
+
+	rollback, err := migration.Rollback(migrations, applied, 5)
+	if got := versions(rollback); err != nil || len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Errorf("got %v, %v, want [2 1]", got, err)
+	}
+
+	applied[3] = migration.Checksum("select 3")
+	if _, err := migration.Rollback(migrations, applied, 1); !errors.Is(err, migration.ErrIrreversible) {
+		t.Errorf("got error %v, want ErrIrreversible", err)
+	}
+}
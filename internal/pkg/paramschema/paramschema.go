@@ -0,0 +1,364 @@
+package paramschema
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	stringType   string = "string"
+	intType      string = "int"
+	floatType    string = "float"
+	boolType     string = "bool"
+	dateType     string = "date"
+	datetimeType string = "datetime"
+	uuidType     string = "uuid"
+	jsonType     string = "json"
+
+	dateLayout string = "2006-01-02"
+
+	outDirection string = "out"
+
+	// DefaultMaxItems bounds the length of a list param without max_items
+	DefaultMaxItems int = 1000
+)
+
+// regexCache holds the compiled param regexes by pattern
+var regexCache sync.Map
+
+// FieldError describes why a param value was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid param of a request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+
+	return "invalid params: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the input against the params declared by a target.
+// It returns the declared params converted to their type, undeclared keys are dropped.
+// When no param is declared, the input is returned unchanged but its lists and objects are refused, only a
+// param declared with list can be expanded and only a json param takes an object. Out params of procedures are set by the database, they are skipped.
+func Validate(params []config.Param, input map[string]any) (map[string]any, error) {
+	if len(params) == 0 {
+		return validateUndeclared(input)
+	}
+
+	output := make(map[string]any, len(params))
+	var fieldErrors []FieldError
+
+	for _, param := range params {
+		if param.Direction == outDirection {
+			continue
+		}
+
+		raw, exist := input[param.Name]
+		if !exist || raw == nil {
+			raw = param.Default
+		}
+
+		if raw == nil {
+			if param.Required {
+				fieldErrors = append(fieldErrors, FieldError{param.Name, "is required"})
+				continue
+			}
+			output[param.Name] = nil
+			continue
+		}
+
+		convertFn := convert
+		if param.List {
+			convertFn = convertList
+		}
+
+		value, err := convertFn(param, raw)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{param.Name, err.Error()})
+			continue
+		}
+
+		output[param.Name] = value
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, &ValidationError{Fields: fieldErrors}
+	}
+
+	return output, nil
+}
+
+// validateUndeclared refuses the list and object values of a target without declared params
+func validateUndeclared(input map[string]any) (map[string]any, error) {
+	var fieldErrors []FieldError
+	for name, value := range input {
+		switch value.(type) {
+		case []any:
+			fieldErrors = append(fieldErrors, FieldError{name, "must not be a list, the param isn't declared with list"})
+		case map[string]any:
+			fieldErrors = append(fieldErrors, FieldError{name, "must be a scalar value, the param isn't declared with the json type"})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		slices.SortFunc(fieldErrors, func(a FieldError, b FieldError) int { return strings.Compare(a.Field, b.Field) })
+		return nil, &ValidationError{Fields: fieldErrors}
+	}
+
+	return input, nil
+}
+
+// convertList converts each item of a list param, the constraints of the param apply to every item
+func convertList(param config.Param, raw any) (any, error) {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a list")
+	}
+
+	if len(items) == 0 && !param.AllowEmpty {
+		return nil, fmt.Errorf("must not be empty")
+	}
+
+	maxItems := param.MaxItems
+	if maxItems == 0 {
+		maxItems = DefaultMaxItems
+	}
+	if len(items) > maxItems {
+		return nil, fmt.Errorf("must have at most %d items", maxItems)
+	}
+
+	values := make(sqlqueryhelper.List, len(items))
+	for idx, item := range items {
+		value, err := convert(param, item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d %w", idx, err)
+		}
+		values[idx] = value
+	}
+
+	return values, nil
+}
+
+// convert converts a raw value to the param type and checks its constraints
+func convert(param config.Param, raw any) (any, error) {
+	var (
+		value any
+		err   error
+	)
+
+	switch param.Type {
+	case stringType:
+		value, err = toString(raw)
+	case intType:
+		value, err = toInt(raw)
+	case floatType:
+		value, err = toFloat(raw)
+	case boolType:
+		value, err = toBool(raw)
+	case dateType:
+		value, err = toTime(raw, dateLayout)
+	case datetimeType:
+		value, err = toTime(raw, time.RFC3339)
+	case uuidType:
+		value, err = toUUID(raw)
+	case jsonType:
+		value, err = toJSON(raw)
+	default:
+		err = fmt.Errorf("unknown type %s", param.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkConstraints(param, raw, value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func checkConstraints(param config.Param, raw any, value any) error {
+	var size float64
+	switch typedValue := value.(type) {
+	case string:
+		size = float64(utf8.RuneCountInString(typedValue))
+	case int64:
+		size = float64(typedValue)
+	case float64:
+		size = typedValue
+	}
+
+	if param.Min != nil && size < *param.Min {
+		return fmt.Errorf("must be greater than or equal to %v", *param.Min)
+	}
+	if param.Max != nil && size > *param.Max {
+		return fmt.Errorf("must be less than or equal to %v", *param.Max)
+	}
+
+	if param.Regex != "" {
+		re, err := compileRegex(param.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex configured: %w", err)
+		}
+		if !re.MatchString(fmt.Sprint(raw)) {
+			return fmt.Errorf("must match %s", param.Regex)
+		}
+	}
+
+	if len(param.Enum) > 0 {
+		formatted := fmt.Sprint(value)
+		if _, isTime := value.(time.Time); isTime {
+			formatted = fmt.Sprint(raw)
+		}
+		if !slices.Contains(param.Enum, formatted) {
+			return fmt.Errorf("must be one of %s", strings.Join(param.Enum, ", "))
+		}
+	}
+
+	return nil
+}
+
+// compileRegex compiles a param regex once, the patterns being checked when the configuration is loaded
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, cached := regexCache.Load(pattern); cached {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+
+	return re, nil
+}
+
+func toString(raw any) (string, error) {
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("must be a string")
+	}
+
+	return value, nil
+}
+
+func toInt(raw any) (int64, error) {
+	switch typedRaw := raw.(type) {
+	case float64:
+		if typedRaw != math.Trunc(typedRaw) || math.Abs(typedRaw) > math.MaxInt64 {
+			return 0, fmt.Errorf("must be an integer")
+		}
+		return int64(typedRaw), nil
+	case int:
+		return int64(typedRaw), nil
+	case int64:
+		return typedRaw, nil
+	case string:
+		value, err := strconv.ParseInt(strings.TrimSpace(typedRaw), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("must be an integer")
+		}
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("must be an integer")
+}
+
+func toFloat(raw any) (float64, error) {
+	switch typedRaw := raw.(type) {
+	case float64:
+		return typedRaw, nil
+	case int:
+		return float64(typedRaw), nil
+	case int64:
+		return float64(typedRaw), nil
+	case string:
+		value, err := strconv.ParseFloat(strings.TrimSpace(typedRaw), 64)
+		if err != nil {
+			return 0, fmt.Errorf("must be a number")
+		}
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("must be a number")
+}
+
+func toBool(raw any) (bool, error) {
+	switch typedRaw := raw.(type) {
+	case bool:
+		return typedRaw, nil
+	case string:
+		value, err := strconv.ParseBool(strings.TrimSpace(typedRaw))
+		if err != nil {
+			return false, fmt.Errorf("must be a boolean")
+		}
+		return value, nil
+	}
+
+	return false, fmt.Errorf("must be a boolean")
+}
+
+func toTime(raw any, layout string) (time.Time, error) {
+	value, ok := raw.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("must be a string formatted as %s", layout)
+	}
+
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be formatted as %s", layout)
+	}
+
+	return parsed, nil
+}
+
+func toUUID(raw any) (string, error) {
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("must be a uuid")
+	}
+
+	parsed, err := uuid.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("must be a uuid")
+	}
+
+	return parsed.String(), nil
+}
+
+func toJSON(raw any) (string, error) {
+	if value, ok := raw.(string); ok && json.Valid([]byte(value)) {
+		return value, nil
+	}
+
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("must be a json value")
+	}
+
+	return string(content), nil
+}
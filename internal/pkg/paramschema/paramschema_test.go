@@ -0,0 +1,214 @@
+package paramschema_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/paramschema"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func float(value float64) *float64 {
+	return &value
+}
+
+func TestValidate_WithoutSchemaReturnsInput(t *testing.T) {
+	t.Parallel()
+
+	input := map[string]any{"id": "1"}
+	output, err := paramschema.Validate(nil, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, input) {
+		t.Errorf("got %v, want %v", output, input)
+	}
+}
+
+func TestValidate_WithoutSchemaRejectsListsAndObjects(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]any, 5000)
+	for idx := range ids {
+		ids[idx] = idx
+	}
+
+	_, err := paramschema.Validate(nil, map[string]any{"ids": ids, "filter": map[string]any{"id": 1}, "name": "bob"})
+
+	var validationErr *paramschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	fields := make([]string, len(validationErr.Fields))
+	for idx, field := range validationErr.Fields {
+		fields[idx] = field.Field
+	}
+	if !reflect.DeepEqual(fields, []string{"filter", "ids"}) {
+		t.Errorf("got invalid fields %v, want filter and ids", fields)
+	}
+}
+
+func TestValidate_ConvertsDeclaredParams(t *testing.T) {
+	t.Parallel()
+
+	params := []config.Param{
+		{Name: "name", Type: "string", Required: true, Min: float(2), Max: float(10), Regex: "^[a-z]+$"},
+		{Name: "age", Type: "int", Min: float(0), Max: float(120)},
+		{Name: "ratio", Type: "float"},
+		{Name: "active", Type: "bool", Default: true},
+		{Name: "birthday", Type: "date"},
+		{Name: "created_at", Type: "datetime"},
+		{Name: "uid", Type: "uuid"},
+		{Name: "payload", Type: "json"},
+		{Name: "class", Type: "string", Enum: []string{"A", "B"}},
+		{Name: "comment", Type: "string"},
+	}
+
+	input := map[string]any{
+		"name":       "bob",
+		"age":        "15",
+		"ratio":      0.5,
+		"birthday":   "2010-05-04",
+		"created_at": "2024-01-02T03:04:05Z",
+		"uid":        "F47AC10B-58CC-4372-A567-0E02B2C3D479",
+		"payload":    map[string]any{"key": "value"},
+		"class":      "B",
+		"undeclared": "dropped",
+	}
+
+	output, err := paramschema.Validate(params, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"name":       "bob",
+		"age":        int64(15),
+		"ratio":      0.5,
+		"active":     true,
+		"birthday":   time.Date(2010, 5, 4, 0, 0, 0, 0, time.UTC),
+		"created_at": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"uid":        "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		"payload":    `{"key":"value"}`,
+		"class":      "B",
+		"comment":    nil,
+	}
+
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %#v, want %#v", output, expected)
+	}
+}
+
+func TestValidate_ReturnEveryInvalidField(t *testing.T) {
+	t.Parallel()
+
+	params := []config.Param{
+		{Name: "id", Type: "int", Required: true},
+		{Name: "name", Type: "string", Min: float(3)},
+		{Name: "age", Type: "int", Max: float(120)},
+		{Name: "code", Type: "string", Regex: "^[A-Z]{3}$"},
+		{Name: "class", Type: "string", Enum: []string{"A", "B"}},
+		{Name: "uid", Type: "uuid"},
+		{Name: "birthday", Type: "date"},
+		{Name: "active", Type: "bool"},
+		{Name: "ratio", Type: "float"},
+		{Name: "count", Type: "int"},
+	}
+
+	input := map[string]any{
+		"name":     "ab",
+		"age":      150,
+		"code":     "abc",
+		"class":    "C",
+		"uid":      "not-a-uuid",
+		"birthday": "04/05/2010",
+		"active":   "yes-no",
+		"ratio":    "x",
+		"count":    1.5,
+	}
+
+	_, err := paramschema.Validate(params, input)
+
+	var validationErr *paramschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	if len(validationErr.Fields) != len(params) {
+		t.Errorf("got %d invalid fields, want %d: %v", len(validationErr.Fields), len(params), validationErr.Fields)
+	}
+}
+
+func TestValidate_ConvertsListParams(t *testing.T) {
+	t.Parallel()
+
+	params := []config.Param{
+		{Name: "ids", Type: "int", List: true, Max: float(100)},
+		{Name: "names", Type: "string", List: true, AllowEmpty: true},
+	}
+
+	output, err := paramschema.Validate(params, map[string]any{"ids": []any{1.0, "2", 3}, "names": []any{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]any{"ids": sqlqueryhelper.List{int64(1), int64(2), int64(3)}, "names": sqlqueryhelper.List{}}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %#v, want %#v", output, expected)
+	}
+}
+
+func TestValidate_RejectsInvalidListParams(t *testing.T) {
+	t.Parallel()
+
+	params := []config.Param{
+		{Name: "ids", Type: "int", List: true, MaxItems: 2},
+	}
+
+	testCases := []struct {
+		value    any
+		expected string
+	}{
+		{"1,2", "must be a list"},
+		{[]any{}, "must not be empty"},
+		{[]any{1, 2, 3}, "must have at most 2 items"},
+		{[]any{1, "x"}, "item 1 must be an integer"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := paramschema.Validate(params, map[string]any{"ids": testCase.value})
+
+		var validationErr *paramschema.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("wrong error for %v: %v", testCase.value, err)
+		}
+		if message := validationErr.Fields[0].Message; message != testCase.expected {
+			t.Errorf("got message %q for %v, want %q", message, testCase.value, testCase.expected)
+		}
+	}
+}
+
+func TestValidate_SkipsOutParams(t *testing.T) {
+	t.Parallel()
+
+	params := []config.Param{
+		{Name: "account", Type: "int", Required: true, Direction: "in"},
+		{Name: "balance", Type: "float", Required: true, Direction: "out"},
+		{Name: "total", Type: "int", Direction: "inout"},
+	}
+
+	output, err := paramschema.Validate(params, map[string]any{"account": "3", "balance": "not a float", "total": 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]any{"account": int64(3), "total": int64(10)}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %v, want %v", output, expected)
+	}
+}
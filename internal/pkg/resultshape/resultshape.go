@@ -0,0 +1,265 @@
+package resultshape
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const arraySuffix string = "[]"
+
+var ErrInvalidShape error = errors.New("invalid result shape")
+
+// level holds the fields of a document, or of an object or an array element of a document
+type level struct {
+	columns map[string]string
+	objects map[string]*level
+	arrays  map[string]*level
+}
+
+func newLevel() *level {
+	return &level{columns: map[string]string{}, objects: map[string]*level{}, arrays: map[string]*level{}}
+}
+
+// Nest groups flat rows, such as the rows of a joined query, into documents having the same values for the key
+// columns. The other columns are paths separated by dots : a column named lines[].product fills the product of the
+// elements of the lines array, a column named teacher.name fills the name of the teacher object. Array elements
+// having the same values are merged, elements whose values are all null (as the unmatched rows of a left join) are
+// skipped, and an object whose values are all null becomes null. Documents and elements keep the order of the rows.
+func Nest(rows []map[string]any, key []string) ([]map[string]any, error) {
+	if len(rows) == 0 {
+		return rows, nil
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for column := range rows[0] {
+		columns = append(columns, column)
+	}
+
+	root, err := parseLevel(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range key {
+		if _, exists := root.columns[column]; !exists {
+			return nil, fmt.Errorf("%w: key column %s is not a column of the document", ErrInvalidShape, column)
+		}
+	}
+
+	documents := newCollection()
+	for _, row := range rows {
+		keyValues := make([]any, len(key))
+		for idx, column := range key {
+			keyValues[idx] = row[column]
+		}
+
+		document, err := documents.get(root, keyValues, row)
+		if err != nil {
+			return nil, err
+		}
+		if err := document.add(row); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]map[string]any, len(documents.items))
+	for idx, document := range documents.items {
+		result[idx] = document.value()
+	}
+
+	return result, nil
+}
+
+// parseLevel builds the levels of a document from the paths of the columns
+func parseLevel(columns []string) (*level, error) {
+	root := newLevel()
+
+	for _, column := range columns {
+		segments := strings.Split(column, ".")
+		current := root
+
+		for idx, segment := range segments {
+			field, isArray := strings.CutSuffix(segment, arraySuffix)
+			if field == "" {
+				return nil, fmt.Errorf("%w: column %s has an empty field", ErrInvalidShape, column)
+			}
+
+			if idx == len(segments)-1 {
+				if isArray {
+					return nil, fmt.Errorf("%w: column %s ends with an array", ErrInvalidShape, column)
+				}
+				if current.has(field) {
+					return nil, fmt.Errorf("%w: field %s of column %s is already used", ErrInvalidShape, field, column)
+				}
+				current.columns[field] = column
+				break
+			}
+
+			fields, other := current.objects, current.arrays
+			if isArray {
+				fields, other = current.arrays, current.objects
+			}
+			if _, exists := other[field]; exists {
+				return nil, fmt.Errorf("%w: field %s of column %s is both an object and an array", ErrInvalidShape, field, column)
+			}
+			if _, exists := current.columns[field]; exists {
+				return nil, fmt.Errorf("%w: field %s of column %s is already used", ErrInvalidShape, field, column)
+			}
+
+			next, exists := fields[field]
+			if !exists {
+				next = newLevel()
+				fields[field] = next
+			}
+			current = next
+		}
+	}
+
+	return root, nil
+}
+
+func (l *level) has(field string) bool {
+	_, isColumn := l.columns[field]
+	_, isObject := l.objects[field]
+	_, isArray := l.arrays[field]
+
+	return isColumn || isObject || isArray
+}
+
+// identity returns the values of the level and of its objects, arrays excluded, in the order of their fields
+func (l *level) identity(row map[string]any) []any {
+	var values []any
+	for _, field := range slices.Sorted(maps.Keys(l.columns)) {
+		values = append(values, row[l.columns[field]])
+	}
+	for _, field := range slices.Sorted(maps.Keys(l.objects)) {
+		values = append(values, l.objects[field].identity(row)...)
+	}
+
+	return values
+}
+
+func (l *level) hasArrays() bool {
+	if len(l.arrays) > 0 {
+		return true
+	}
+	for _, object := range l.objects {
+		if object.hasArrays() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collection holds the distinct documents of an array, in the order of their first row
+type collection struct {
+	index map[string]*document
+	items []*document
+}
+
+func newCollection() *collection {
+	return &collection{index: map[string]*document{}}
+}
+
+// get returns the document of the identity values, built from the row when it is new
+func (c *collection) get(l *level, identity []any, row map[string]any) (*document, error) {
+	encoded, err := json.Marshal(identity)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShape, err)
+	}
+
+	if document, exists := c.index[string(encoded)]; exists {
+		return document, nil
+	}
+
+	document := &document{collections: map[*level]*collection{}}
+	document.values = document.build(l, row)
+	c.index[string(encoded)] = document
+	c.items = append(c.items, document)
+
+	return document, nil
+}
+
+// slot is an array field of a document, or of one of its objects, filled once every row is read
+type slot struct {
+	target map[string]any
+	field  string
+	items  *collection
+}
+
+type document struct {
+	values      map[string]any
+	collections map[*level]*collection
+	slots       []slot
+}
+
+// build fills the fields and the objects of a level, its arrays are filled by add
+func (d *document) build(l *level, row map[string]any) map[string]any {
+	values := make(map[string]any, len(l.columns)+len(l.objects)+len(l.arrays))
+	for field, column := range l.columns {
+		values[field] = row[column]
+	}
+
+	for field, object := range l.objects {
+		if object.hasArrays() || !allNil(object.identity(row)) {
+			values[field] = d.build(object, row)
+		} else {
+			values[field] = nil
+		}
+	}
+
+	for field, array := range l.arrays {
+		items := newCollection()
+		d.collections[array] = items
+		d.slots = append(d.slots, slot{target: values, field: field, items: items})
+	}
+
+	return values
+}
+
+// add adds the array elements of a row to the document
+func (d *document) add(row map[string]any) error {
+	for array, items := range d.collections {
+		identity := array.identity(row)
+		if len(identity) > 0 && allNil(identity) {
+			continue
+		}
+
+		element, err := items.get(array, identity, row)
+		if err != nil {
+			return err
+		}
+		if err := element.add(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *document) value() map[string]any {
+	for _, slot := range d.slots {
+		elements := make([]any, len(slot.items.items))
+		for idx, element := range slot.items.items {
+			elements[idx] = element.value()
+		}
+		slot.target[slot.field] = elements
+	}
+
+	return d.values
+}
+
+func allNil(values []any) bool {
+	for _, value := range values {
+		if value != nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,83 @@
+package resultshape_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/resultshape"
+
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNest(t *testing.T) {
+	t.Parallel()
+
+	row := func(schoolId int, school string, director any, classId any, class any, studentName any) map[string]any {
+		return map[string]any{
+			"id":                     schoolId,
+			"name":                   school,
+			"director.name":          director,
+			"classes[].id":           classId,
+			"classes[].name":         class,
+			"classes[].students[].n": studentName,
+		}
+	}
+
+	rows := []map[string]any{
+		row(1, "north", "bob", 10, "A", "alice"),
+		row(1, "north", "bob", 10, "A", "carol"),
+		row(1, "north", "bob", 11, "B", nil),
+		row(2, "south", nil, nil, nil, nil),
+		row(1, "north", "bob", 10, "A", "dave"),
+	}
+
+	documents, err := resultshape.Nest(rows, []string{"id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := json.Marshal(documents)
+	expected := `[` +
+		`{"classes":[{"id":10,"name":"A","students":[{"n":"alice"},{"n":"carol"},{"n":"dave"}]},{"id":11,"name":"B","students":[]}],"director":{"name":"bob"},"id":1,"name":"north"},` +
+		`{"classes":[],"director":null,"id":2,"name":"south"}` +
+		`]`
+
+	if string(got) != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
+
+func TestNest_WithoutRowsReturnsRows(t *testing.T) {
+	t.Parallel()
+
+	documents, err := resultshape.Nest(nil, []string{"id"})
+	if err != nil || documents != nil {
+		t.Errorf("got %v, %v, want no documents", documents, err)
+	}
+}
+
+func TestNest_RejectsInvalidShapes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		columns []string
+		key     []string
+	}{
+		{[]string{"id", "name"}, []string{"uid"}},
+		{[]string{"id", "lines[].id"}, []string{"lines[].id"}},
+		{[]string{"id", "lines[]"}, []string{"id"}},
+		{[]string{"id", "lines.id", "lines[].name"}, []string{"id"}},
+		{[]string{"id", "teacher", "teacher.name"}, []string{"id"}},
+		{[]string{"id", "teacher..name"}, []string{"id"}},
+	}
+
+	for _, testCase := range testCases {
+		row := make(map[string]any, len(testCase.columns))
+		for _, column := range testCase.columns {
+			row[column] = 1
+		}
+
+		if _, err := resultshape.Nest([]map[string]any{row}, testCase.key); !errors.Is(err, resultshape.ErrInvalidShape) {
+			t.Errorf("got error %v with columns %v and key %v, want ErrInvalidShape", err, testCase.columns, testCase.key)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package sqllexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dialect names, as returned by the gorm dialector of a connection
+const (
+	MySQLDialect     string = "mysql"
+	PostgresDialect  string = "postgres"
+	SQLServerDialect string = "sqlserver"
+	SQLiteDialect    string = "sqlite"
+)
+
+type TokenKind int
+
+const (
+	Whitespace TokenKind = iota
+	Comment
+	Word
+	QuotedIdentifier
+	String
+	Number
+	Punct
+)
+
+// Token is a lexical unit of a SQL text, Pos is its byte offset in the text
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+// IsKeyword reports whether the token is the word keyword, case insensitively
+func (t Token) IsKeyword(keyword string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, keyword)
+}
+
+// IsPunct reports whether the token is the punctuation punct
+func (t Token) IsPunct(punct string) bool {
+	return t.Kind == Punct && t.Text == punct
+}
+
+// Upper returns the token text in upper case
+func (t Token) Upper() string {
+	return strings.ToUpper(t.Text)
+}
+
+type lexer struct {
+	dialect string
+	input   string
+	pos     int
+}
+
+// Tokenize splits a SQL text into tokens. Quoting and comment rules follow the dialect:
+// # comments and backslash escapes for mysql, dollar quoting and nested comments for postgres,
+// bracket identifiers for sqlserver and sqlite. Unterminated strings or comments end at the end of the text.
+func Tokenize(dialect string, input string) []Token {
+	l := &lexer{dialect: dialect, input: input}
+	tokens := make([]Token, 0, len(input)/4)
+
+	for l.pos < len(l.input) {
+		start := l.pos
+		kind := l.next()
+		tokens = append(tokens, Token{Kind: kind, Text: l.input[start:l.pos], Pos: start})
+	}
+
+	return tokens
+}
+
+// Significant returns the tokens without whitespaces and comments
+func Significant(tokens []Token) []Token {
+	significant := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Kind != Whitespace && token.Kind != Comment {
+			significant = append(significant, token)
+		}
+	}
+
+	return significant
+}
+
+func (l *lexer) next() TokenKind {
+	r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+
+	switch {
+	case unicode.IsSpace(r):
+		for l.pos < len(l.input) {
+			r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			l.pos += size
+		}
+		return Whitespace
+	case l.hasPrefix("--") || (r == '#' && l.dialect == MySQLDialect):
+		l.skipUntil("\n")
+		return Comment
+	case l.hasPrefix("/*"):
+		l.skipBlockComment()
+		return Comment
+	case r == '\'':
+		l.skipQuoted('\'', l.dialect == MySQLDialect)
+		return String
+	case (r == 'E' || r == 'e') && l.dialect == PostgresDialect && l.peekAt(1) == '\'':
+		l.pos++
+		l.skipQuoted('\'', true)
+		return String
+	case (r == 'N' || r == 'n') && l.peekAt(1) == '\'':
+		l.pos++
+		l.skipQuoted('\'', l.dialect == MySQLDialect)
+		return String
+	case r == '"':
+		l.skipQuoted('"', l.dialect == MySQLDialect)
+		return QuotedIdentifier
+	case r == '`':
+		l.skipQuoted('`', false)
+		return QuotedIdentifier
+	case r == '[' && (l.dialect == SQLServerDialect || l.dialect == SQLiteDialect):
+		l.skipQuoted(']', false)
+		return QuotedIdentifier
+	case r == '$' && l.dialect == PostgresDialect:
+		if tag, ok := l.dollarTag(); ok {
+			l.pos += len(tag)
+			l.skipUntil(tag)
+			return String
+		}
+		l.pos += size
+		return Punct
+	case unicode.IsLetter(r) || r == '_':
+		l.skipWord()
+		return Word
+	case unicode.IsDigit(r):
+		l.skipWord()
+		return Number
+	default:
+		l.pos += size
+		return Punct
+	}
+}
+
+func (l *lexer) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(l.input[l.pos:], prefix)
+}
+
+func (l *lexer) peekAt(offset int) byte {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+
+	return l.input[l.pos+offset]
+}
+
+// skipUntil moves after the next occurrence of end, or to the end of the text
+func (l *lexer) skipUntil(end string) {
+	if idx := strings.Index(l.input[l.pos:], end); idx >= 0 {
+		l.pos += idx + len(end)
+		return
+	}
+
+	l.pos = len(l.input)
+}
+
+func (l *lexer) skipBlockComment() {
+	depth := 0
+	for l.pos < len(l.input) {
+		switch {
+		case l.hasPrefix("/*"):
+			depth++
+			l.pos += 2
+		case l.hasPrefix("*/"):
+			depth--
+			l.pos += 2
+			if depth == 0 || l.dialect != PostgresDialect {
+				return
+			}
+		default:
+			l.pos++
+		}
+	}
+}
+
+// skipQuoted moves after the closing quote, a doubled quote is part of the content
+func (l *lexer) skipQuoted(quote byte, backslashEscape bool) {
+	l.pos++
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case backslashEscape && c == '\\':
+			l.pos += 2
+		case c == quote && l.peekAt(1) == quote:
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			return
+		default:
+			l.pos++
+		}
+	}
+
+	l.pos = min(l.pos, len(l.input))
+}
+
+func (l *lexer) skipWord() {
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$' {
+			break
+		}
+		l.pos += size
+	}
+}
+
+// dollarTag returns the $tag$ opening a postgres dollar-quoted string at the current position
+func (l *lexer) dollarTag() (string, bool) {
+	for idx := l.pos + 1; idx < len(l.input); idx++ {
+		c := l.input[idx]
+		switch {
+		case c == '$':
+			return l.input[l.pos : idx+1], true
+		case c == '_' || unicode.IsLetter(rune(c)) || (idx > l.pos+1 && unicode.IsDigit(rune(c))):
+			continue
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
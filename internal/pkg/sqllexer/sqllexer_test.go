@@ -0,0 +1,56 @@
+package sqllexer_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"reflect"
+	"testing"
+)
+
+func texts(tokens []sqllexer.Token) []string {
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, token.Text)
+	}
+
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		input    string
+		expected []string
+	}{
+		{"postgres", "select 'a;b' from t -- c;d\n;", []string{"select", "'a;b'", "from", "t", ";"}},
+		{"postgres", "select $$x;y$$, $body$ $$ $body$, $1", []string{"select", "$$x;y$$", ",", "$body$ $$ $body$", ",", "$", "1"}},
+		{"postgres", "select E'it\\'s', 'it''s'", []string{"select", "E'it\\'s'", ",", "'it''s'"}},
+		{"mysql", "select 'it\\'s' # comment;\n, `a;b`", []string{"select", "'it\\'s'", ",", "`a;b`"}},
+		{"sqlserver", "select [a;b], N'x;y' from t", []string{"select", "[a;b]", ",", "N'x;y'", "from", "t"}},
+		{"sqlite", "select \"a\"\"b\" /* ; */", []string{"select", "\"a\"\"b\""}},
+	}
+
+	for _, testCase := range testCases {
+		got := texts(sqllexer.Significant(sqllexer.Tokenize(testCase.dialect, testCase.input)))
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("Tokenize(%s, %q) = %q, want %q", testCase.dialect, testCase.input, got, testCase.expected)
+		}
+	}
+}
+
+func TestTokenize_KeepsEveryByte(t *testing.T) {
+	t.Parallel()
+
+	input := "select 1; -- unterminated 'quote\n/* block */ select 'open"
+
+	rebuilt := ""
+	for _, token := range sqllexer.Tokenize("postgres", input) {
+		rebuilt += token.Text
+	}
+
+	if rebuilt != input {
+		t.Errorf("got %q, want %q", rebuilt, input)
+	}
+}
@@ -0,0 +1,138 @@
+package sqlqueryhelper
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+)
+
+// Execution modes of a target, an empty mode lets ReturnsRows decide
+const (
+	QueryMode string = "query"
+	ExecMode  string = "exec"
+)
+
+// rowKeywords are the statements which always return rows
+var rowKeywords = map[string]bool{
+	"SELECT":   true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"SHOW":     true,
+	"EXPLAIN":  true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"PRAGMA":   true,
+}
+
+// writeKeywords are the statements which return rows only with a RETURNING or OUTPUT clause
+var writeKeywords = map[string]bool{
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"MERGE":   true,
+	"REPLACE": true,
+}
+
+// ReturnsRows detects whether a statement returns rows. Comments are ignored, CTEs are skipped
+// to classify the main statement, and write statements with a RETURNING or OUTPUT clause return rows.
+func ReturnsRows(dialect string, query string) bool {
+	return returnsRows(sqllexer.Significant(sqllexer.Tokenize(dialect, query)))
+}
+
+// IsQueryMode resolves the execution mode of a statement
+func IsQueryMode(mode string, dialect string, query string) bool {
+	switch mode {
+	case QueryMode:
+		return true
+	case ExecMode:
+		return false
+	default:
+		return ReturnsRows(dialect, query)
+	}
+}
+
+func returnsRows(tokens []sqllexer.Token) bool {
+	for len(tokens) > 0 && tokens[0].IsPunct("(") {
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 || tokens[0].Kind != sqllexer.Word {
+		return false
+	}
+
+	keyword := tokens[0].Upper()
+	switch {
+	case rowKeywords[keyword]:
+		return true
+	case keyword == "WITH":
+		return returnsRows(skipCommonTableExpressions(tokens[1:]))
+	case writeKeywords[keyword]:
+		return hasTopLevelKeyword(tokens[1:], "RETURNING", "OUTPUT")
+	}
+
+	return false
+}
+
+// skipCommonTableExpressions returns the tokens of the main statement following a WITH clause
+func skipCommonTableExpressions(tokens []sqllexer.Token) []sqllexer.Token {
+	if len(tokens) > 0 && tokens[0].IsKeyword("RECURSIVE") {
+		tokens = tokens[1:]
+	}
+
+	for len(tokens) > 0 {
+		// CTE name and optional column list
+		tokens = tokens[1:]
+		if len(tokens) > 0 && tokens[0].IsPunct("(") {
+			tokens = skipParenthesis(tokens)
+		}
+
+		// AS [NOT] [MATERIALIZED] ( ... )
+		for len(tokens) > 0 && !tokens[0].IsPunct("(") {
+			tokens = tokens[1:]
+		}
+		tokens = skipParenthesis(tokens)
+
+		if len(tokens) == 0 || !tokens[0].IsPunct(",") {
+			break
+		}
+		tokens = tokens[1:]
+	}
+
+	return tokens
+}
+
+// skipParenthesis returns the tokens following the parenthesis group opened by the first token
+func skipParenthesis(tokens []sqllexer.Token) []sqllexer.Token {
+	depth := 0
+	for idx, token := range tokens {
+		switch {
+		case token.IsPunct("("):
+			depth++
+		case token.IsPunct(")"):
+			depth--
+			if depth == 0 {
+				return tokens[idx+1:]
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasTopLevelKeyword(tokens []sqllexer.Token, keywords ...string) bool {
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.IsPunct("("):
+			depth++
+		case token.IsPunct(")"):
+			depth--
+		case depth == 0:
+			for _, keyword := range keywords {
+				if token.IsKeyword(keyword) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package sqlqueryhelper_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"testing"
+)
+
+func TestReturnsRows(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		query    string
+		expected bool
+	}{
+		{sqllexer.SQLiteDialect, "select * from student", true},
+		{sqllexer.SQLiteDialect, "  SELECT 1", true},
+		{sqllexer.PostgresDialect, "-- list students\nselect * from student", true},
+		{sqllexer.PostgresDialect, "/* header /* nested */ */ select 1", true},
+		{sqllexer.MySQLDialect, "# list students\nselect * from student", true},
+		{sqllexer.PostgresDialect, "(select id from a) union (select id from b)", true},
+		{sqllexer.PostgresDialect, "with s as (select * from student) select * from s", true},
+		{sqllexer.PostgresDialect, "with recursive t(n) as (values (1) union all select n+1 from t where n < 5), u as not materialized (select 2) select * from t", true},
+		{sqllexer.PostgresDialect, "with s as (select id from student) delete from student where id in (select id from s)", false},
+		{sqllexer.PostgresDialect, "with s as (select id from student) delete from student where id in (select id from s) returning id", true},
+		{sqllexer.PostgresDialect, "values (1, 'a'), (2, 'b')", true},
+		{sqllexer.MySQLDialect, "show tables", true},
+		{sqllexer.PostgresDialect, "explain select * from student", true},
+		{sqllexer.SQLiteDialect, "pragma table_info(student)", true},
+		{sqllexer.PostgresDialect, "insert into student (name) values ($1) returning id", true},
+		{sqllexer.SQLServerDialect, "insert into student (name) output inserted.* values (@p1)", true},
+		{sqllexer.SQLServerDialect, "update student set name = 'x' output inserted.id where id = @p1", true},
+		{sqllexer.PostgresDialect, "insert into student (name) values ('returning')", false},
+		{sqllexer.PostgresDialect, "insert into student (name) select name from (select 'x' as name returning) t", false},
+		{sqllexer.PostgresDialect, "update student set name = 'bob' where id = $1", false},
+		{sqllexer.PostgresDialect, "delete from student", false},
+		{sqllexer.PostgresDialect, "create table t (id int)", false},
+		{sqllexer.PostgresDialect, "-- select\ninsert into t values (1)", false},
+		{sqllexer.PostgresDialect, "", false},
+	}
+
+	for _, testCase := range testCases {
+		if got := sqlqueryhelper.ReturnsRows(testCase.dialect, testCase.query); got != testCase.expected {
+			t.Errorf("ReturnsRows(%q) = %v, want %v", testCase.query, got, testCase.expected)
+		}
+	}
+}
+
+func TestIsQueryMode_ExplicitModeOverridesDetection(t *testing.T) {
+	t.Parallel()
+
+	if !sqlqueryhelper.IsQueryMode(sqlqueryhelper.QueryMode, sqllexer.MySQLDialect, "call list_students()") {
+		t.Error("query mode must return rows")
+	}
+
+	if sqlqueryhelper.IsQueryMode(sqlqueryhelper.ExecMode, sqllexer.MySQLDialect, "select sleep(1)") {
+		t.Error("exec mode must not return rows")
+	}
+}
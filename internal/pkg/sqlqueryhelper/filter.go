@@ -0,0 +1,88 @@
+package sqlqueryhelper
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"strings"
+)
+
+const filterAlias string = "api_gateway_sql_filter"
+
+// FilterParamPrefix starts the reserved {{param}} names bound to the filter values of a request
+const FilterParamPrefix string = "_filter_"
+
+// FilterOperators maps the operators of a filter to their SQL comparison
+var FilterOperators = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"lt":   "<",
+	"lte":  "<=",
+	"gt":   ">",
+	"gte":  ">=",
+	"like": "LIKE",
+}
+
+// Condition compares a column of the rows to the value bound to the {{Param}} param
+type Condition struct {
+	Column   string
+	Operator string
+	Param    string
+}
+
+// Order sorts the rows by a column
+type Order struct {
+	Column     string
+	Descending bool
+}
+
+// FilterQuery wraps a select query as a derived table to keep its rows matching every condition, sorted by the orders.
+// Without orders the query keeps its own ORDER BY. Columns must be checked by the caller, operators unknown to
+// FilterOperators are compared with =.
+func FilterQuery(dialect string, query string, conditions []Condition, orders []Order) string {
+	if len(conditions) == 0 && len(orders) == 0 {
+		return query
+	}
+
+	query = trimStatement(dialect, query)
+	tokens := sqllexer.Significant(sqllexer.Tokenize(dialect, query))
+
+	if len(orders) > 0 {
+		query = withoutOrderBy(query, tokens)
+	} else if dialect == sqllexer.SQLServerDialect && findTopLevel(tokens, "ORDER", "BY") >= 0 &&
+		findTopLevel(tokens, "TOP") < 0 && findTopLevel(tokens, "OFFSET") < 0 {
+		// sqlserver only accepts an ORDER BY in a derived table limiting its rows
+		query += " OFFSET 0 ROWS"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("SELECT * FROM (" + query + ") AS " + filterAlias)
+
+	for idx, condition := range conditions {
+		if idx == 0 {
+			builder.WriteString(" WHERE ")
+		} else {
+			builder.WriteString(" AND ")
+		}
+
+		operator, exists := FilterOperators[condition.Operator]
+		if !exists {
+			operator = "="
+		}
+		builder.WriteString(filterAlias + "." + QuoteIdentifier(dialect, condition.Column) + " " + operator + " {{" + condition.Param + "}}")
+	}
+
+	for idx, order := range orders {
+		if idx == 0 {
+			builder.WriteString(" ORDER BY ")
+		} else {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString(filterAlias + "." + QuoteIdentifier(dialect, order.Column))
+		if order.Descending {
+			builder.WriteString(" DESC")
+		}
+	}
+
+	return builder.String()
+}
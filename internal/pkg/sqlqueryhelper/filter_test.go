@@ -0,0 +1,80 @@
+package sqlqueryhelper_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"testing"
+)
+
+func TestFilterQuery(t *testing.T) {
+	t.Parallel()
+
+	conditions := []sqlqueryhelper.Condition{
+		{Column: "city", Operator: "eq", Param: "_filter_0"},
+		{Column: "age", Operator: "gte", Param: "_filter_1"},
+	}
+	orders := []sqlqueryhelper.Order{{Column: "created_at", Descending: true}, {Column: "name"}}
+
+	testCases := []struct {
+		dialect    string
+		query      string
+		conditions []sqlqueryhelper.Condition
+		orders     []sqlqueryhelper.Order
+		expected   string
+	}{
+		{
+			sqllexer.PostgresDialect,
+			"select * from student order by id;",
+			nil,
+			nil,
+			"select * from student order by id;",
+		},
+		{
+			sqllexer.PostgresDialect,
+			"select * from student where class_id = {{class}} order by id;",
+			conditions,
+			orders,
+			`SELECT * FROM (select * from student where class_id = {{class}}) AS api_gateway_sql_filter WHERE api_gateway_sql_filter."city" = {{_filter_0}} AND api_gateway_sql_filter."age" >= {{_filter_1}} ORDER BY api_gateway_sql_filter."created_at" DESC, api_gateway_sql_filter."name"`,
+		},
+		{
+			sqllexer.MySQLDialect,
+			"select * from student",
+			conditions[:1],
+			nil,
+			"SELECT * FROM (select * from student) AS api_gateway_sql_filter WHERE api_gateway_sql_filter.`city` = {{_filter_0}}",
+		},
+		{
+			sqllexer.SQLiteDialect,
+			"select * from student order by id",
+			nil,
+			orders[1:],
+			`SELECT * FROM (select * from student) AS api_gateway_sql_filter ORDER BY api_gateway_sql_filter."name"`,
+		},
+		{
+			sqllexer.SQLServerDialect,
+			"select * from student order by id",
+			[]sqlqueryhelper.Condition{{Column: "name", Operator: "like", Param: "_filter_0"}},
+			nil,
+			"SELECT * FROM (select * from student order by id OFFSET 0 ROWS) AS api_gateway_sql_filter WHERE api_gateway_sql_filter.[name] LIKE {{_filter_0}}",
+		},
+	}
+
+	for _, testCase := range testCases {
+		if got := sqlqueryhelper.FilterQuery(testCase.dialect, testCase.query, testCase.conditions, testCase.orders); got != testCase.expected {
+			t.Errorf("FilterQuery(%s, %q) = %q, want %q", testCase.dialect, testCase.query, got, testCase.expected)
+		}
+	}
+}
+
+func TestFilterQuery_ComposesWithPageQuery(t *testing.T) {
+	t.Parallel()
+
+	query := sqlqueryhelper.FilterQuery(sqllexer.SQLServerDialect, "select * from student", nil, []sqlqueryhelper.Order{{Column: "name"}})
+	got := sqlqueryhelper.PageQuery(sqllexer.SQLServerDialect, query, sqlqueryhelper.Page{Limit: 10, Offset: 10})
+	expected := "SELECT * FROM (select * from student) AS api_gateway_sql_filter ORDER BY api_gateway_sql_filter.[name] OFFSET 10 ROWS FETCH NEXT 10 ROWS ONLY"
+
+	if got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
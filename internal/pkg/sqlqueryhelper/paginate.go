@@ -0,0 +1,167 @@
+package sqlqueryhelper
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"strconv"
+	"strings"
+)
+
+// CursorParam is the reserved {{param}} bound to the cursor value of a keyset page
+const CursorParam string = "_cursor"
+
+const (
+	pageAlias  string = "api_gateway_sql_page"
+	countAlias string = "api_gateway_sql_count"
+)
+
+// Page describes the rows of a query to return. Without CursorColumn, Limit rows are returned after Offset rows.
+// With CursorColumn, Limit rows ordered by this column are returned, after the {{_cursor}} value when AfterCursor is set.
+type Page struct {
+	Limit        int
+	Offset       int
+	CursorColumn string
+	Descending   bool
+	AfterCursor  bool
+}
+
+// PageQuery wraps a select query to return a page of its rows, with the LIMIT / OFFSET clauses of the dialect,
+// or OFFSET ... FETCH NEXT for sqlserver
+func PageQuery(dialect string, query string, page Page) string {
+	query = trimStatement(dialect, query)
+	tokens := sqllexer.Significant(sqllexer.Tokenize(dialect, query))
+
+	if page.CursorColumn != "" {
+		return keysetQuery(dialect, query, tokens, page)
+	}
+
+	if dialect == sqllexer.SQLServerDialect {
+		// OFFSET can't follow a query limited by TOP or by its own OFFSET, it is paginated as a derived table
+		if findTopLevel(tokens, "TOP") >= 0 || findTopLevel(tokens, "OFFSET") >= 0 {
+			query = "SELECT * FROM (" + query + ") AS " + pageAlias + " ORDER BY (SELECT NULL)"
+		} else if findTopLevel(tokens, "ORDER", "BY") < 0 {
+			query += " ORDER BY (SELECT NULL)"
+		}
+		return query + fetchClause(page.Offset, page.Limit)
+	}
+
+	// a query which limits its rows itself is paginated as a derived table
+	if findTopLevel(tokens, "LIMIT") >= 0 || findTopLevel(tokens, "OFFSET") >= 0 {
+		query = "SELECT * FROM (" + query + ") AS " + pageAlias
+	}
+
+	return query + " LIMIT " + strconv.Itoa(page.Limit) + " OFFSET " + strconv.Itoa(page.Offset)
+}
+
+// CountQuery wraps a select query to count its rows in a total column
+func CountQuery(dialect string, query string) string {
+	query = trimStatement(dialect, query)
+	tokens := sqllexer.Significant(sqllexer.Tokenize(dialect, query))
+
+	return "SELECT COUNT(*) AS total FROM (" + withoutOrderBy(query, tokens) + ") AS " + countAlias
+}
+
+// QuoteIdentifier quotes a column name for the dialect
+func QuoteIdentifier(dialect string, name string) string {
+	switch dialect {
+	case sqllexer.MySQLDialect:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case sqllexer.SQLServerDialect:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+func keysetQuery(dialect string, query string, tokens []sqllexer.Token, page Page) string {
+	column := pageAlias + "." + QuoteIdentifier(dialect, page.CursorColumn)
+	comparison, direction := " > ", ""
+	if page.Descending {
+		comparison, direction = " < ", " DESC"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("SELECT * FROM (" + withoutOrderBy(query, tokens) + ") AS " + pageAlias)
+	if page.AfterCursor {
+		builder.WriteString(" WHERE " + column + comparison + "{{" + CursorParam + "}}")
+	}
+	builder.WriteString(" ORDER BY " + column + direction)
+
+	if dialect == sqllexer.SQLServerDialect {
+		builder.WriteString(fetchClause(0, page.Limit))
+	} else {
+		builder.WriteString(" LIMIT " + strconv.Itoa(page.Limit))
+	}
+
+	return builder.String()
+}
+
+func fetchClause(offset int, limit int) string {
+	return " OFFSET " + strconv.Itoa(offset) + " ROWS FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+}
+
+// trimStatement removes the spaces and semicolons ending a query
+func trimStatement(dialect string, query string) string {
+	tokens := sqllexer.Significant(sqllexer.Tokenize(dialect, query))
+	for len(tokens) > 0 && tokens[len(tokens)-1].IsPunct(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	last := tokens[len(tokens)-1]
+	return strings.TrimSpace(query[:last.Pos+len(last.Text)])
+}
+
+// withoutOrderBy removes a final top-level ORDER BY clause, unless the query also limits its rows with TOP or LIMIT
+func withoutOrderBy(query string, tokens []sqllexer.Token) string {
+	orderBy := findTopLevel(tokens, "ORDER", "BY")
+	if orderBy < 0 {
+		return query
+	}
+
+	if findTopLevel(tokens, "TOP") >= 0 {
+		return query
+	}
+	for _, keyword := range []string{"LIMIT", "OFFSET", "FETCH", "FOR"} {
+		if findTopLevel(tokens[orderBy:], keyword) >= 0 {
+			return query
+		}
+	}
+
+	return strings.TrimSpace(query[:tokens[orderBy].Pos])
+}
+
+// findTopLevel returns the index of the last top-level occurrence of the keywords sequence, or -1
+func findTopLevel(tokens []sqllexer.Token, keywords ...string) int {
+	found := -1
+	depth := 0
+
+	for idx, token := range tokens {
+		switch {
+		case token.IsPunct("("):
+			depth++
+		case token.IsPunct(")"):
+			depth--
+		case depth == 0 && matchKeywords(tokens[idx:], keywords):
+			found = idx
+		}
+	}
+
+	return found
+}
+
+func matchKeywords(tokens []sqllexer.Token, keywords []string) bool {
+	if len(tokens) < len(keywords) {
+		return false
+	}
+
+	for idx, keyword := range keywords {
+		if !tokens[idx].IsKeyword(keyword) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package sqlqueryhelper_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"testing"
+)
+
+func TestPageQuery_OffsetPages(t *testing.T) {
+	t.Parallel()
+
+	page := sqlqueryhelper.Page{Limit: 10, Offset: 20}
+
+	testCases := []struct {
+		dialect  string
+		query    string
+		expected string
+	}{
+		{sqllexer.SQLiteDialect, "select * from student;", "select * from student LIMIT 10 OFFSET 20"},
+		{sqllexer.PostgresDialect, "select * from student order by name", "select * from student order by name LIMIT 10 OFFSET 20"},
+		{sqllexer.MySQLDialect, "select * from student where name = {{name}} ; ", "select * from student where name = {{name}} LIMIT 10 OFFSET 20"},
+		{sqllexer.MySQLDialect, "select * from student limit 100", "SELECT * FROM (select * from student limit 100) AS api_gateway_sql_page LIMIT 10 OFFSET 20"},
+		{sqllexer.PostgresDialect, "select * from student where id in (select id from class limit 5)", "select * from student where id in (select id from class limit 5) LIMIT 10 OFFSET 20"},
+		{sqllexer.SQLServerDialect, "select * from student", "select * from student ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{sqllexer.SQLServerDialect, "select * from student order by name", "select * from student order by name OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{sqllexer.SQLServerDialect, "select top 50 * from student order by name", "SELECT * FROM (select top 50 * from student order by name) AS api_gateway_sql_page ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+	}
+
+	for _, testCase := range testCases {
+		if got := sqlqueryhelper.PageQuery(testCase.dialect, testCase.query, page); got != testCase.expected {
+			t.Errorf("PageQuery(%s, %q) = %q, want %q", testCase.dialect, testCase.query, got, testCase.expected)
+		}
+	}
+}
+
+func TestPageQuery_KeysetPages(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		query    string
+		page     sqlqueryhelper.Page
+		expected string
+	}{
+		{
+			sqllexer.SQLiteDialect,
+			"select * from student order by name",
+			sqlqueryhelper.Page{Limit: 11, CursorColumn: "id"},
+			`SELECT * FROM (select * from student) AS api_gateway_sql_page ORDER BY api_gateway_sql_page."id" LIMIT 11`,
+		},
+		{
+			sqllexer.PostgresDialect,
+			"select * from student where class = {{class}}",
+			sqlqueryhelper.Page{Limit: 11, CursorColumn: "id", AfterCursor: true},
+			`SELECT * FROM (select * from student where class = {{class}}) AS api_gateway_sql_page WHERE api_gateway_sql_page."id" > {{_cursor}} ORDER BY api_gateway_sql_page."id" LIMIT 11`,
+		},
+		{
+			sqllexer.MySQLDialect,
+			"select * from student",
+			sqlqueryhelper.Page{Limit: 6, CursorColumn: "created_at", Descending: true, AfterCursor: true},
+			"SELECT * FROM (select * from student) AS api_gateway_sql_page WHERE api_gateway_sql_page.`created_at` < {{_cursor}} ORDER BY api_gateway_sql_page.`created_at` DESC LIMIT 6",
+		},
+		{
+			sqllexer.SQLServerDialect,
+			"select * from student order by name",
+			sqlqueryhelper.Page{Limit: 11, CursorColumn: "id", AfterCursor: true},
+			"SELECT * FROM (select * from student) AS api_gateway_sql_page WHERE api_gateway_sql_page.[id] > {{_cursor}} ORDER BY api_gateway_sql_page.[id] OFFSET 0 ROWS FETCH NEXT 11 ROWS ONLY",
+		},
+	}
+
+	for _, testCase := range testCases {
+		if got := sqlqueryhelper.PageQuery(testCase.dialect, testCase.query, testCase.page); got != testCase.expected {
+			t.Errorf("PageQuery(%s, %q) = %q, want %q", testCase.dialect, testCase.query, got, testCase.expected)
+		}
+	}
+}
+
+func TestCountQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		query    string
+		expected string
+	}{
+		{sqllexer.PostgresDialect, "select * from student order by name;", "SELECT COUNT(*) AS total FROM (select * from student) AS api_gateway_sql_count"},
+		{sqllexer.MySQLDialect, "select * from student order by name limit 5", "SELECT COUNT(*) AS total FROM (select * from student order by name limit 5) AS api_gateway_sql_count"},
+		{sqllexer.SQLServerDialect, "select name, count(*) from student group by name order by count(*) desc", "SELECT COUNT(*) AS total FROM (select name, count(*) from student group by name) AS api_gateway_sql_count"},
+	}
+
+	for _, testCase := range testCases {
+		if got := sqlqueryhelper.CountQuery(testCase.dialect, testCase.query); got != testCase.expected {
+			t.Errorf("CountQuery(%s, %q) = %q, want %q", testCase.dialect, testCase.query, got, testCase.expected)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		name     string
+		expected string
+	}{
+		{sqllexer.MySQLDialect, "na`me", "`na``me`"},
+		{sqllexer.SQLServerDialect, "na]me", "[na]]me]"},
+		{sqllexer.PostgresDialect, `na"me`, `"na""me"`},
+		{sqllexer.SQLiteDialect, "name", `"name"`},
+	}
+
+	for _, testCase := range testCases {
+		if got := sqlqueryhelper.QuoteIdentifier(testCase.dialect, testCase.name); got != testCase.expected {
+			t.Errorf("QuoteIdentifier(%s, %q) = %q, want %q", testCase.dialect, testCase.name, got, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package sqlqueryhelper
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Directions of a procedure param
+const (
+	InDirection    string = "in"
+	OutDirection   string = "out"
+	InOutDirection string = "inout"
+)
+
+// sessionVariablePrefix names the mysql session variables receiving the out params
+const sessionVariablePrefix string = "@api_gateway_sql_"
+
+var ErrProcedureUnsupported error = errors.New("datasource doesn't support procedure and function calls")
+
+// ProcedureParam is an argument of a procedure or a function, in the order of its declaration
+type ProcedureParam struct {
+	Name      string
+	Direction string
+}
+
+// Call holds the statements calling a procedure, their {{param}} placeholders are bound by TransformQuery
+type Call struct {
+	// Setup sets the session variables of the inout params before the call (mysql)
+	Setup string
+	// Query calls the procedure. With sqlserver it is the procedure name alone, called with named params.
+	Query string
+	// Fetch selects the out params from their session variables after the call (mysql)
+	Fetch string
+	// OutRow tells the call returns the out params as its single row (postgres)
+	OutRow bool
+}
+
+// CallQuery builds the call of a procedure for a dialect : CALL name(...) for postgres and mysql, the procedure
+// name for sqlserver, whose params are bound by name. Postgres out params are passed as NULL, mysql out params
+// are passed as session variables read back by the Fetch statement.
+func CallQuery(dialect string, procedure string, params []ProcedureParam) (Call, error) {
+	args := make([]string, len(params))
+
+	switch dialect {
+	case sqllexer.PostgresDialect:
+		call := Call{}
+		for idx, param := range params {
+			args[idx] = "{{" + param.Name + "}}"
+			if param.Direction == OutDirection {
+				args[idx] = "NULL"
+			}
+			call.OutRow = call.OutRow || isOutParam(param)
+		}
+		call.Query = "CALL " + procedure + "(" + strings.Join(args, ", ") + ")"
+
+		return call, nil
+	case sqllexer.MySQLDialect:
+		var setup, fetch []string
+		for idx, param := range params {
+			if !isOutParam(param) {
+				args[idx] = "{{" + param.Name + "}}"
+				continue
+			}
+
+			variable := sessionVariablePrefix + param.Name
+			args[idx] = variable
+			if param.Direction == InOutDirection {
+				setup = append(setup, variable+" = {{"+param.Name+"}}")
+			}
+			fetch = append(fetch, variable+" AS "+QuoteIdentifier(dialect, param.Name))
+		}
+
+		call := Call{Query: "CALL " + procedure + "(" + strings.Join(args, ", ") + ")"}
+		if len(setup) > 0 {
+			call.Setup = "SET " + strings.Join(setup, ", ")
+		}
+		if len(fetch) > 0 {
+			call.Fetch = "SELECT " + strings.Join(fetch, ", ")
+		}
+
+		return call, nil
+	case sqllexer.SQLServerDialect:
+		return Call{Query: procedure}, nil
+	default:
+		return Call{}, fmt.Errorf("%w: %s", ErrProcedureUnsupported, dialect)
+	}
+}
+
+// FunctionQuery builds the select of a function for a dialect : SELECT * FROM name(...) for the set returning
+// functions of postgres and the table-valued functions of sqlserver, SELECT name(...) for the scalar functions
+// of mysql. Out params are not arguments of a function call.
+func FunctionQuery(dialect string, function string, params []ProcedureParam) (string, error) {
+	args := make([]string, 0, len(params))
+	for _, param := range params {
+		if param.Direction != OutDirection {
+			args = append(args, "{{"+param.Name+"}}")
+		}
+	}
+	call := function + "(" + strings.Join(args, ", ") + ")"
+
+	switch dialect {
+	case sqllexer.PostgresDialect, sqllexer.SQLServerDialect:
+		return "SELECT * FROM " + call, nil
+	case sqllexer.MySQLDialect:
+		return "SELECT " + call + " AS " + QuoteIdentifier(dialect, function[strings.LastIndex(function, ".")+1:]), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrProcedureUnsupported, dialect)
+	}
+}
+
+func isOutParam(param ProcedureParam) bool {
+	return param.Direction == OutDirection || param.Direction == InOutDirection
+}
@@ -0,0 +1,103 @@
+package sqlqueryhelper_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"errors"
+	"testing"
+)
+
+var procedureParams = []sqlqueryhelper.ProcedureParam{
+	{Name: "account", Direction: sqlqueryhelper.InDirection},
+	{Name: "balance", Direction: sqlqueryhelper.OutDirection},
+	{Name: "total", Direction: sqlqueryhelper.InOutDirection},
+	{Name: "label"},
+}
+
+func TestCallQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		params   []sqlqueryhelper.ProcedureParam
+		expected sqlqueryhelper.Call
+	}{
+		{
+			sqllexer.PostgresDialect,
+			procedureParams,
+			sqlqueryhelper.Call{Query: "CALL bank.transfer({{account}}, NULL, {{total}}, {{label}})", OutRow: true},
+		},
+		{
+			sqllexer.PostgresDialect,
+			procedureParams[:1],
+			sqlqueryhelper.Call{Query: "CALL bank.transfer({{account}})"},
+		},
+		{
+			sqllexer.MySQLDialect,
+			procedureParams,
+			sqlqueryhelper.Call{
+				Setup: "SET @api_gateway_sql_total = {{total}}",
+				Query: "CALL bank.transfer({{account}}, @api_gateway_sql_balance, @api_gateway_sql_total, {{label}})",
+				Fetch: "SELECT @api_gateway_sql_balance AS `balance`, @api_gateway_sql_total AS `total`",
+			},
+		},
+		{
+			sqllexer.MySQLDialect,
+			nil,
+			sqlqueryhelper.Call{Query: "CALL bank.transfer()"},
+		},
+		{
+			sqllexer.SQLServerDialect,
+			procedureParams,
+			sqlqueryhelper.Call{Query: "bank.transfer"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		got, err := sqlqueryhelper.CallQuery(testCase.dialect, "bank.transfer", testCase.params)
+		if err != nil {
+			t.Fatalf("unexpected error with %s: %v", testCase.dialect, err)
+		}
+
+		if got != testCase.expected {
+			t.Errorf("CallQuery(%s) = %+v, want %+v", testCase.dialect, got, testCase.expected)
+		}
+	}
+}
+
+func TestFunctionQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		expected string
+	}{
+		{sqllexer.PostgresDialect, "SELECT * FROM bank.balance({{account}}, {{total}}, {{label}})"},
+		{sqllexer.SQLServerDialect, "SELECT * FROM bank.balance({{account}}, {{total}}, {{label}})"},
+		{sqllexer.MySQLDialect, "SELECT bank.balance({{account}}, {{total}}, {{label}}) AS `balance`"},
+	}
+
+	for _, testCase := range testCases {
+		got, err := sqlqueryhelper.FunctionQuery(testCase.dialect, "bank.balance", procedureParams)
+		if err != nil {
+			t.Fatalf("unexpected error with %s: %v", testCase.dialect, err)
+		}
+
+		if got != testCase.expected {
+			t.Errorf("FunctionQuery(%s) = %q, want %q", testCase.dialect, got, testCase.expected)
+		}
+	}
+}
+
+func TestCallQuery_RejectsSQLite(t *testing.T) {
+	t.Parallel()
+
+	if _, err := sqlqueryhelper.CallQuery(sqllexer.SQLiteDialect, "transfer", nil); !errors.Is(err, sqlqueryhelper.ErrProcedureUnsupported) {
+		t.Errorf("got error %v from CallQuery, want ErrProcedureUnsupported", err)
+	}
+
+	if _, err := sqlqueryhelper.FunctionQuery(sqllexer.SQLiteDialect, "balance", nil); !errors.Is(err, sqlqueryhelper.ErrProcedureUnsupported) {
+		t.Errorf("got error %v from FunctionQuery, want ErrProcedureUnsupported", err)
+	}
+}
@@ -1,34 +1,125 @@
 package sqlqueryhelper
 
 import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-// TransformQuery used to parse query from config target
-func TransformQuery(sqlQuery string, params map[string]any) (string, []any) {
-	re := regexp.MustCompile(`{{(\w+)}}`)
-	matches := re.FindAllStringSubmatch(sqlQuery, -1)
+// List is the value of a list param, expanded by TransformQuery into one placeholder per item
+type List []any
+
+// ErrUnboundParam tells a {{param}} of a query has no value, or a value which can't be bound
+var ErrUnboundParam error = errors.New("param can't be bound")
+
+// paramRegex matches {{param}}, and {{step.column}} for the results of a previous step
+var paramRegex = regexp.MustCompile(`{{(\w+(?:\.\w+)?)}}`)
+
+// TransformQuery used to parse query from config target.
+// Each {{param}} is replaced by the placeholder of the dialect: $n for postgres, @pn for sqlserver and ? otherwise.
+// With numbered placeholders, a param used several times is bound once and reuses its number.
+// A List value is expanded into one placeholder per item, for IN ({{param}}) clauses, and an empty
+// list into a subquery without rows, so that IN is false and NOT IN is true.
+// The first {{param}} without value, or whose value is another list or an object, returns ErrUnboundParam
+// instead of reaching the database.
+func TransformQuery(dialect string, sqlQuery string, params map[string]any) (string, []any, error) {
+	matches := paramRegex.FindAllStringSubmatch(sqlQuery, -1)
 
 	values := make([]any, 0, len(matches))
-	transformedQuery := re.ReplaceAllStringFunc(sqlQuery, func(param string) string {
+	rendered := make(map[string]string, len(matches))
+	bind := func(value any) string {
+		values = append(values, value)
+		return Placeholder(dialect, len(values))
+	}
+
+	var err error
+	transformedQuery := paramRegex.ReplaceAllStringFunc(sqlQuery, func(param string) string {
 		paramName := param[2 : len(param)-2]
-		if value, exists := params[paramName]; exists {
-			values = append(values, value)
-			return "?"
+		value, exists := params[paramName]
+		if !exists {
+			if err == nil {
+				err = fmt.Errorf("%w: %s has no value", ErrUnboundParam, param)
+			}
+			return param
 		}
-		return param
+
+		if placeholders, bound := rendered[paramName]; bound && isNumbered(dialect) {
+			return placeholders
+		}
+
+		var placeholders string
+		switch typedValue := value.(type) {
+		case List:
+			if len(typedValue) == 0 {
+				placeholders = emptyList(dialect)
+				break
+			}
+			itemPlaceholders := make([]string, len(typedValue))
+			for idx, item := range typedValue {
+				itemPlaceholders[idx] = bind(item)
+			}
+			placeholders = strings.Join(itemPlaceholders, ", ")
+		case []any, map[string]any:
+			if err == nil {
+				err = fmt.Errorf("%w: %s is not a scalar value", ErrUnboundParam, param)
+			}
+			return param
+		default:
+			placeholders = bind(value)
+		}
+
+		rendered[paramName] = placeholders
+		return placeholders
 	})
 
-	return transformedQuery, values
+	if err != nil {
+		return "", nil, err
+	}
+
+	return transformedQuery, values, nil
+}
+
+// ParamNames returns the names of the {{param}} placeholders of a query, in their first order of appearance
+func ParamNames(sqlQuery string) []string {
+	matches := paramRegex.FindAllStringSubmatch(sqlQuery, -1)
+
+	names := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+
+	return names
+}
+
+// Placeholder returns the placeholder of the n-th bound value (starting at 1) for a dialect
+func Placeholder(dialect string, position int) string {
+	switch dialect {
+	case sqllexer.PostgresDialect:
+		return "$" + strconv.Itoa(position)
+	case sqllexer.SQLServerDialect:
+		return "@p" + strconv.Itoa(position)
+	default:
+		return "?"
+	}
 }
 
-// IsSelectQuery used to detect whether a string query is a SELECT or no
-func IsSelectQuery(query string) bool {
-	trimmed := strings.TrimSpace(query)
-	if len(trimmed) < 6 {
-		return false
+// emptyList returns a subquery without rows, the items of an empty IN list
+func emptyList(dialect string) string {
+	if dialect == sqllexer.MySQLDialect {
+		return "SELECT NULL FROM DUAL WHERE 1 = 0"
 	}
 
-	return strings.EqualFold(trimmed[:6], "SELECT")
+	return "SELECT NULL WHERE 1 = 0"
+}
+
+func isNumbered(dialect string) bool {
+	return dialect == sqllexer.PostgresDialect || dialect == sqllexer.SQLServerDialect
 }
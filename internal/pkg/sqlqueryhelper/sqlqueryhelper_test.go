@@ -0,0 +1,162 @@
+package sqlqueryhelper_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTransformQuery_RendersDialectPlaceholders(t *testing.T) {
+	t.Parallel()
+
+	query := "select * from student where class_id = {{class}} and (name = {{name}} or nickname = {{name}}) and age >= {{age}}"
+	params := map[string]any{"class": 1, "name": "bob", "age": 15}
+
+	testCases := []struct {
+		dialect        string
+		expectedQuery  string
+		expectedValues []any
+	}{
+		{
+			sqllexer.PostgresDialect,
+			"select * from student where class_id = $1 and (name = $2 or nickname = $2) and age >= $3",
+			[]any{1, "bob", 15},
+		},
+		{
+			sqllexer.SQLServerDialect,
+			"select * from student where class_id = @p1 and (name = @p2 or nickname = @p2) and age >= @p3",
+			[]any{1, "bob", 15},
+		},
+		{
+			sqllexer.MySQLDialect,
+			"select * from student where class_id = ? and (name = ? or nickname = ?) and age >= ?",
+			[]any{1, "bob", "bob", 15},
+		},
+		{
+			sqllexer.SQLiteDialect,
+			"select * from student where class_id = ? and (name = ? or nickname = ?) and age >= ?",
+			[]any{1, "bob", "bob", 15},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.dialect, func(subT *testing.T) {
+			transformedQuery, values, err := sqlqueryhelper.TransformQuery(testCase.dialect, query, params)
+			if err != nil {
+				subT.Fatalf("unexpected error: %v", err)
+			}
+
+			if transformedQuery != testCase.expectedQuery {
+				subT.Errorf("got query %q, want %q", transformedQuery, testCase.expectedQuery)
+			}
+
+			if !reflect.DeepEqual(values, testCase.expectedValues) {
+				subT.Errorf("got values %v, want %v", values, testCase.expectedValues)
+			}
+		})
+	}
+}
+
+func TestTransformQuery_RejectsUnboundParams(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		query  string
+		params map[string]any
+	}{
+		{"select {{a}}, {{b}}, {{a}}", map[string]any{"a": 1}},
+		{"select * from student where a = {{x}}", map[string]any{"x": map[string]any{"id": 1}}},
+		{"select * from student where id in ({{ids}})", map[string]any{"ids": []any{1, 2}}},
+	}
+
+	for _, testCase := range testCases {
+		if _, _, err := sqlqueryhelper.TransformQuery(sqllexer.MySQLDialect, testCase.query, testCase.params); !errors.Is(err, sqlqueryhelper.ErrUnboundParam) {
+			t.Errorf("wrong error for %q with %v: %v", testCase.query, testCase.params, err)
+		}
+	}
+}
+
+func TestTransformQuery_ExpandsListParams(t *testing.T) {
+	t.Parallel()
+
+	query := "select * from student where id in ({{ids}}) and class_id not in ({{classes}}) and name = {{name}} or id in ({{ids}})"
+	params := map[string]any{"ids": sqlqueryhelper.List{1, 2, 3}, "classes": sqlqueryhelper.List{}, "name": "bob"}
+
+	testCases := []struct {
+		dialect        string
+		expectedQuery  string
+		expectedValues []any
+	}{
+		{
+			sqllexer.PostgresDialect,
+			"select * from student where id in ($1, $2, $3) and class_id not in (SELECT NULL WHERE 1 = 0) and name = $4 or id in ($1, $2, $3)",
+			[]any{1, 2, 3, "bob"},
+		},
+		{
+			sqllexer.SQLServerDialect,
+			"select * from student where id in (@p1, @p2, @p3) and class_id not in (SELECT NULL WHERE 1 = 0) and name = @p4 or id in (@p1, @p2, @p3)",
+			[]any{1, 2, 3, "bob"},
+		},
+		{
+			sqllexer.MySQLDialect,
+			"select * from student where id in (?, ?, ?) and class_id not in (SELECT NULL FROM DUAL WHERE 1 = 0) and name = ? or id in (?, ?, ?)",
+			[]any{1, 2, 3, "bob", 1, 2, 3},
+		},
+		{
+			sqllexer.SQLiteDialect,
+			"select * from student where id in (?, ?, ?) and class_id not in (SELECT NULL WHERE 1 = 0) and name = ? or id in (?, ?, ?)",
+			[]any{1, 2, 3, "bob", 1, 2, 3},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.dialect, func(subT *testing.T) {
+			transformedQuery, values, err := sqlqueryhelper.TransformQuery(testCase.dialect, query, params)
+			if err != nil {
+				subT.Fatalf("unexpected error: %v", err)
+			}
+
+			if transformedQuery != testCase.expectedQuery {
+				subT.Errorf("got query %q, want %q", transformedQuery, testCase.expectedQuery)
+			}
+
+			if !reflect.DeepEqual(values, testCase.expectedValues) {
+				subT.Errorf("got values %v, want %v", values, testCase.expectedValues)
+			}
+		})
+	}
+}
+
+func TestTransformQuery_BindsStepResultParams(t *testing.T) {
+	t.Parallel()
+
+	transformedQuery, values, err := sqlqueryhelper.TransformQuery(sqllexer.PostgresDialect, "insert into line values ({{header.id}}, {{product}}, {{header.id}})", map[string]any{"header.id": int64(7), "product": "pen"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if transformedQuery != "insert into line values ($1, $2, $1)" {
+		t.Errorf("got query %q", transformedQuery)
+	}
+
+	if !reflect.DeepEqual(values, []any{int64(7), "pen"}) {
+		t.Errorf("got values %v", values)
+	}
+}
+
+func TestParamNames_ReturnsEachParamOnce(t *testing.T) {
+	t.Parallel()
+
+	query := "insert into student (name, nickname, class_id) values ({{name}}, {{name}}, {{class.id}})"
+	expected := []string{"name", "class.id"}
+
+	if names := sqlqueryhelper.ParamNames(query); !reflect.DeepEqual(names, expected) {
+		t.Errorf("got %v, want %v", names, expected)
+	}
+	if names := sqlqueryhelper.ParamNames("delete from student"); len(names) != 0 {
+		t.Errorf("got %v, want no params", names)
+	}
+}
@@ -0,0 +1,90 @@
+package sqlqueryhelper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidTemplate error = errors.New("invalid sql template")
+
+var (
+	templateTagRegex     = regexp.MustCompile(`{{\s*(?:#if\s+(\w+(?:\.\w+)?)|(else)|(/if))\s*}}`)
+	unknownTemplateRegex = regexp.MustCompile(`{{\s*[#/]`)
+)
+
+type templateBlock struct {
+	parentEmitting bool
+	condition      bool
+	inElse         bool
+}
+
+// RenderTemplate keeps the sections of a query whose conditions hold :
+// {{#if param}} ... {{else}} ... {{/if}}, blocks can be nested and {{else}} is optional.
+// A param holds when it is set to a value other than null, an empty string or an empty list.
+// Param values are only tested, never written in the query, the {{param}} placeholders are left for TransformQuery.
+func RenderTemplate(sqlQuery string, params map[string]any) (string, error) {
+	if tag := unknownTemplateRegex.FindString(templateTagRegex.ReplaceAllString(sqlQuery, "")); tag != "" {
+		return "", fmt.Errorf("%w: unknown tag %s", ErrInvalidTemplate, tag)
+	}
+
+	tags := templateTagRegex.FindAllStringSubmatchIndex(sqlQuery, -1)
+	if len(tags) == 0 {
+		return sqlQuery, nil
+	}
+
+	var (
+		builder  strings.Builder
+		blocks   []templateBlock
+		emitting = true
+		last     = 0
+	)
+
+	for _, tag := range tags {
+		if emitting {
+			builder.WriteString(sqlQuery[last:tag[0]])
+		}
+		last = tag[1]
+
+		switch {
+		case tag[2] >= 0:
+			condition := isSet(params, sqlQuery[tag[2]:tag[3]])
+			blocks = append(blocks, templateBlock{parentEmitting: emitting, condition: condition})
+			emitting = emitting && condition
+		case tag[4] >= 0:
+			if len(blocks) == 0 || blocks[len(blocks)-1].inElse {
+				return "", fmt.Errorf("%w: {{else}} without {{#if}}", ErrInvalidTemplate)
+			}
+			block := &blocks[len(blocks)-1]
+			block.inElse = true
+			emitting = block.parentEmitting && !block.condition
+		default:
+			if len(blocks) == 0 {
+				return "", fmt.Errorf("%w: {{/if}} without {{#if}}", ErrInvalidTemplate)
+			}
+			emitting = blocks[len(blocks)-1].parentEmitting
+			blocks = blocks[:len(blocks)-1]
+		}
+	}
+
+	if len(blocks) > 0 {
+		return "", fmt.Errorf("%w: {{#if}} without {{/if}}", ErrInvalidTemplate)
+	}
+	builder.WriteString(sqlQuery[last:])
+
+	return builder.String(), nil
+}
+
+func isSet(params map[string]any, name string) bool {
+	switch value := params[name].(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	case []any:
+		return len(value) > 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,117 @@
+package sqlqueryhelper_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	query := "select * from student where 1 = 1{{#if city}} and city = {{city}}{{/if}}" +
+		"{{#if class}} and class_id = {{class}}{{#if age}} and age >= {{age}}{{/if}}{{else}} and class_id is null{{/if}}"
+
+	testCases := []struct {
+		params   map[string]any
+		expected string
+	}{
+		{
+			map[string]any{},
+			"select * from student where 1 = 1 and class_id is null",
+		},
+		{
+			map[string]any{"city": "Paris", "class": 1, "age": 15},
+			"select * from student where 1 = 1 and city = {{city}} and class_id = {{class}} and age >= {{age}}",
+		},
+		{
+			map[string]any{"city": "", "class": 1, "age": nil},
+			"select * from student where 1 = 1 and class_id = {{class}}",
+		},
+		{
+			map[string]any{"city": []any{}, "age": 15},
+			"select * from student where 1 = 1 and class_id is null",
+		},
+		{
+			map[string]any{"class": false},
+			"select * from student where 1 = 1 and class_id = {{class}}",
+		},
+	}
+
+	for _, testCase := range testCases {
+		got, err := sqlqueryhelper.RenderTemplate(query, testCase.params)
+		if err != nil {
+			t.Fatalf("unexpected error with %v: %v", testCase.params, err)
+		}
+
+		if got != testCase.expected {
+			t.Errorf("got %q with %v, want %q", got, testCase.params, testCase.expected)
+		}
+	}
+}
+
+func TestRenderTemplate_WithoutTagsReturnsQuery(t *testing.T) {
+	t.Parallel()
+
+	query := "select * from student where id = {{id}}"
+	if got, err := sqlqueryhelper.RenderTemplate(query, nil); err != nil || got != query {
+		t.Errorf("got %q, %v, want the query unchanged", got, err)
+	}
+}
+
+func TestRenderTemplate_RejectsMalformedTemplates(t *testing.T) {
+	t.Parallel()
+
+	queries := []string{
+		"select * from student {{#if city}} where city = {{city}}",
+		"select * from student where city = {{city}} {{/if}}",
+		"select * from student {{else}}",
+		"select * from student {{#if a}} {{else}} {{else}} {{/if}}",
+		"select * from student {{#each ids}} {{/each}}",
+		"select * from student {{#if a}} {{#unless b}} {{/if}}",
+	}
+
+	for _, query := range queries {
+		if _, err := sqlqueryhelper.RenderTemplate(query, map[string]any{"a": 1}); !errors.Is(err, sqlqueryhelper.ErrInvalidTemplate) {
+			t.Errorf("got error %v for %q, want ErrInvalidTemplate", err, query)
+		}
+	}
+}
+
+func TestRenderTemplate_ParamValuesNeverReachTheQuery(t *testing.T) {
+	t.Parallel()
+
+	query := "select * from student where 1 = 1{{#if city}} and city = {{city}}{{/if}}{{#if name}} and name = {{name}}{{/if}}"
+	injections := []string{
+		"{{/if}} or 1 = 1 --",
+		"{{#if x}}{{else}}' or '1' = '1{{/if}}",
+		"'; drop table student; --",
+		"{{name}}",
+	}
+
+	for _, injection := range injections {
+		params := map[string]any{"city": injection, "name": "bob"}
+
+		rendered, err := sqlqueryhelper.RenderTemplate(query, params)
+		if err != nil {
+			t.Fatalf("unexpected error with %q: %v", injection, err)
+		}
+
+		transformedQuery, values, err := sqlqueryhelper.TransformQuery(sqllexer.PostgresDialect, rendered, params)
+		if err != nil {
+			t.Fatalf("unexpected error with %q: %v", injection, err)
+		}
+
+		expectedQuery := "select * from student where 1 = 1 and city = $1 and name = $2"
+		if transformedQuery != expectedQuery {
+			t.Errorf("got query %q with %q, want %q", transformedQuery, injection, expectedQuery)
+		}
+
+		if !reflect.DeepEqual(values, []any{injection, "bob"}) {
+			t.Errorf("got values %v, want %q bound as a value", values, injection)
+		}
+	}
+}
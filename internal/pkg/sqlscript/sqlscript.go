@@ -0,0 +1,224 @@
+package sqlscript
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultDelimiter string = ";"
+
+// ErrBatchCount refuses a sqlserver GO separator with a count, which would run its batch several times
+var ErrBatchCount error = errors.New("GO with a count is not supported")
+
+// routineKinds are the objects whose body may contain the statement delimiter
+var routineKinds = map[string]bool{
+	"TRIGGER":   true,
+	"PROCEDURE": true,
+	"PROC":      true,
+	"FUNCTION":  true,
+	"EVENT":     true,
+}
+
+// splitter holds the state of a script being cut into statements
+type splitter struct {
+	dialect    string
+	script     string
+	delimiter  string
+	statements []string
+
+	start      int
+	tokens     []sqllexer.Token
+	parenDepth int
+	blockDepth int
+}
+
+// Split cuts a SQL script into statements, following the rules of the dialect :
+//   - the delimiter is ignored inside strings, quoted identifiers, comments, parenthesis and postgres dollar-quoted bodies
+//   - the delimiter is ignored inside the BEGIN ... END blocks of triggers, procedures, functions and events
+//   - mysql DELIMITER directives change the delimiter
+//   - sqlserver GO lines end a batch, and a procedure, function or trigger runs until the end of its batch
+//
+// Statements are returned without their delimiter, statements holding only comments are dropped. A GO line with
+// a count returns ErrBatchCount, the statements of a script are run once.
+func Split(dialect string, script string) ([]string, error) {
+	s := &splitter{dialect: dialect, script: script, delimiter: defaultDelimiter}
+	tokens := sqllexer.Tokenize(dialect, script)
+
+	for idx := 0; idx < len(tokens); idx++ {
+		token := tokens[idx]
+
+		switch {
+		case token.Kind == sqllexer.Whitespace || token.Kind == sqllexer.Comment:
+			continue
+		case s.dialect == sqllexer.MySQLDialect && len(s.tokens) == 0 && token.IsKeyword("DELIMITER"):
+			lineEnd := s.lineEnd(token.Pos)
+			if delimiter := strings.TrimSpace(script[token.Pos+len(token.Text) : lineEnd]); delimiter != "" {
+				s.delimiter = delimiter
+			}
+			idx = skipTo(tokens, idx, lineEnd)
+			s.start = lineEnd
+			continue
+		case s.dialect == sqllexer.SQLServerDialect && token.IsKeyword("GO") && s.isBatchSeparator(token):
+			lineEnd := s.lineEnd(token.Pos)
+			if count := strings.TrimSpace(script[token.Pos+len(token.Text) : lineEnd]); count != "" {
+				return nil, fmt.Errorf("%w: GO %s on line %d", ErrBatchCount, count, strings.Count(script[:token.Pos], "\n")+1)
+			}
+			s.flush(token.Pos)
+			idx = skipTo(tokens, idx, lineEnd)
+			s.start = lineEnd
+			continue
+		case s.delimiter != defaultDelimiter:
+			// with a custom delimiter, as the mysql client does, only the delimiter ends a statement
+			offset := s.customDelimiterIndex(token)
+			if offset < 0 {
+				s.tokens = append(s.tokens, token)
+				continue
+			}
+			if offset > 0 {
+				s.tokens = append(s.tokens, token)
+			}
+			s.flush(token.Pos + offset)
+			delimiterEnd := token.Pos + offset + len(s.delimiter)
+			idx = skipTo(tokens, idx, delimiterEnd)
+			s.start = delimiterEnd
+			continue
+		case s.parenDepth == 0 && s.blockDepth == 0 && !s.runsToBatchEnd() && strings.HasPrefix(script[token.Pos:], s.delimiter):
+			s.flush(token.Pos)
+			delimiterEnd := token.Pos + len(s.delimiter)
+			idx = skipTo(tokens, idx, delimiterEnd)
+			s.start = delimiterEnd
+			continue
+		}
+
+		s.track(tokens, idx)
+		s.tokens = append(s.tokens, token)
+	}
+
+	s.flush(len(script))
+	return s.statements, nil
+}
+
+// track updates the parenthesis and block depths with the token at idx
+func (s *splitter) track(tokens []sqllexer.Token, idx int) {
+	token := tokens[idx]
+
+	switch {
+	case token.IsPunct("("):
+		s.parenDepth++
+	case token.IsPunct(")"):
+		s.parenDepth = max(s.parenDepth-1, 0)
+	case !s.isRoutine():
+		return
+	case token.IsKeyword("CASE") && len(s.tokens) > 0 && s.tokens[len(s.tokens)-1].IsKeyword("END"):
+		// END CASE closes the block opened by CASE
+		return
+	case token.IsKeyword("BEGIN") || token.IsKeyword("CASE"):
+		s.blockDepth++
+	case token.IsKeyword("END"):
+		// END IF, END LOOP, END WHILE and END REPEAT close blocks which are not counted
+		next := nextSignificant(tokens, idx)
+		if next != nil && (next.IsKeyword("IF") || next.IsKeyword("LOOP") || next.IsKeyword("WHILE") || next.IsKeyword("REPEAT")) {
+			return
+		}
+		s.blockDepth = max(s.blockDepth-1, 0)
+	}
+}
+
+// isRoutine reports whether the current statement creates a trigger, procedure, function or event
+func (s *splitter) isRoutine() bool {
+	if len(s.tokens) == 0 || !(s.tokens[0].IsKeyword("CREATE") || s.tokens[0].IsKeyword("ALTER")) {
+		return false
+	}
+
+	for _, token := range s.tokens[1:] {
+		if token.Kind != sqllexer.Word {
+			continue
+		}
+		keyword := token.Upper()
+		if routineKinds[keyword] {
+			return true
+		}
+		if keyword == "TABLE" || keyword == "VIEW" || keyword == "INDEX" || keyword == "AS" || keyword == "ON" {
+			return false
+		}
+	}
+
+	return false
+}
+
+// customDelimiterIndex returns the offset of a custom delimiter in the token, or -1.
+// A custom delimiter may be glued to a word, like END$$, since mysql words can hold a $.
+func (s *splitter) customDelimiterIndex(token sqllexer.Token) int {
+	if token.Kind == sqllexer.String || token.Kind == sqllexer.QuotedIdentifier {
+		return -1
+	}
+
+	if offset := strings.Index(token.Text, s.delimiter); offset >= 0 {
+		return offset
+	}
+
+	// the delimiter may span several punctuation tokens, like //
+	if strings.HasPrefix(s.script[token.Pos:], s.delimiter) {
+		return 0
+	}
+
+	return -1
+}
+
+// runsToBatchEnd reports whether the current statement is a sqlserver routine, whose body ends with its batch
+func (s *splitter) runsToBatchEnd() bool {
+	return s.dialect == sqllexer.SQLServerDialect && s.isRoutine()
+}
+
+// isBatchSeparator reports whether a GO token stands alone on its line, optionally followed by a count
+func (s *splitter) isBatchSeparator(token sqllexer.Token) bool {
+	lineStart := strings.LastIndex(s.script[:token.Pos], "\n") + 1
+	if strings.TrimSpace(s.script[lineStart:token.Pos]) != "" {
+		return false
+	}
+
+	rest := strings.TrimSpace(s.script[token.Pos+len(token.Text) : s.lineEnd(token.Pos)])
+	return strings.Trim(rest, "0123456789") == ""
+}
+
+func (s *splitter) lineEnd(pos int) int {
+	if idx := strings.Index(s.script[pos:], "\n"); idx >= 0 {
+		return pos + idx
+	}
+
+	return len(s.script)
+}
+
+// flush appends the current statement, ending at end, and starts a new one
+func (s *splitter) flush(end int) {
+	if len(s.tokens) > 0 {
+		s.statements = append(s.statements, strings.TrimSpace(s.script[s.start:end]))
+	}
+
+	s.start = end
+	s.tokens = s.tokens[:0]
+	s.parenDepth = 0
+	s.blockDepth = 0
+}
+
+// skipTo returns the index of the last token starting before pos
+func skipTo(tokens []sqllexer.Token, idx int, pos int) int {
+	for idx+1 < len(tokens) && tokens[idx+1].Pos < pos {
+		idx++
+	}
+
+	return idx
+}
+
+func nextSignificant(tokens []sqllexer.Token, idx int) *sqllexer.Token {
+	for next := idx + 1; next < len(tokens); next++ {
+		if tokens[next].Kind != sqllexer.Whitespace && tokens[next].Kind != sqllexer.Comment {
+			return &tokens[next]
+		}
+	}
+
+	return nil
+}
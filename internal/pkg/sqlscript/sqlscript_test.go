@@ -0,0 +1,120 @@
+package sqlscript_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlscript"
+
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected statements of the testdata corpus")
+
+// TestSplit_Corpus splits every testdata/<dialect>/*.sql script and compares the statements with the .json file next to it
+func TestSplit_Corpus(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*", "*.sql"))
+	if err != nil {
+		t.Fatalf("failed to list testdata: %v", err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no script found in testdata")
+	}
+
+	for _, script := range scripts {
+		dialect := filepath.Base(filepath.Dir(script))
+		golden := strings.TrimSuffix(script, ".sql") + ".json"
+
+		t.Run(dialect+"/"+filepath.Base(script), func(t *testing.T) {
+			content, err := os.ReadFile(script)
+			if err != nil {
+				t.Fatalf("failed to read script: %v", err)
+			}
+
+			got, err := sqlscript.Split(dialect, string(content))
+			if err != nil {
+				t.Fatalf("failed to split script: %v", err)
+			}
+
+			if *update {
+				data, err := json.MarshalIndent(got, "", "  ")
+				if err != nil {
+					t.Fatalf("failed to encode statements: %v", err)
+				}
+				if err := os.WriteFile(golden, append(data, '\n'), 0o644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+				return
+			}
+
+			data, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+
+			var expected []string
+			if err := json.Unmarshal(data, &expected); err != nil {
+				t.Fatalf("failed to decode golden file: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("got %d statements, want %d\ngot:  %q\nwant: %q", len(got), len(expected), got, expected)
+			}
+		})
+	}
+}
+
+func TestSplit_SchoolFixtures(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dialect  string
+		filename string
+	}{
+		{"mysql", "school_mariadb.sql"},
+		{"mysql", "school_mysql.sql"},
+		{"postgres", "school_postgres.sql"},
+		{"sqlserver", "school_sqlserver.sql"},
+		{"sqlite", "school_sqlite.sql"},
+	}
+
+	for _, testCase := range testCases {
+		content, err := os.ReadFile(filepath.Join("..", "..", "..", "fixtures", "sql", testCase.filename))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", testCase.filename, err)
+		}
+
+		// the fixtures hold no routine, so each semicolon ends a statement
+		expected := strings.Count(string(content), ";")
+		got, err := sqlscript.Split(testCase.dialect, string(content))
+		if err != nil {
+			t.Fatalf("failed to split %s: %v", testCase.filename, err)
+		}
+		if len(got) != expected {
+			t.Errorf("%s: got %d statements, want %d", testCase.filename, len(got), expected)
+		}
+	}
+}
+
+func TestSplit_Empty(t *testing.T) {
+	t.Parallel()
+
+	for _, script := range []string{"", "  \n", "-- only a comment;\n", ";;"} {
+		if got, err := sqlscript.Split("postgres", script); err != nil || len(got) != 0 {
+			t.Errorf("Split(%q) = %q, %v, want no statement", script, got, err)
+		}
+	}
+}
+
+func TestSplit_RejectsBatchCount(t *testing.T) {
+	t.Parallel()
+
+	script := "INSERT INTO audit (message) VALUES (N'tick');\nGO 3\nSELECT 1;"
+	if _, err := sqlscript.Split("sqlserver", script); !errors.Is(err, sqlscript.ErrBatchCount) {
+		t.Errorf("got error %v, want ErrBatchCount", err)
+	}
+}
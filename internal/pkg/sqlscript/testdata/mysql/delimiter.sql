@@ -0,0 +1,18 @@
+-- Routines declared with a custom delimiter, as exported by mysqldump
+CREATE TABLE audit (id INT AUTO_INCREMENT PRIMARY KEY, message VARCHAR(255));
+
+DELIMITER $$
+CREATE DEFINER=`root`@`localhost` PROCEDURE add_audit(IN msg VARCHAR(255))
+BEGIN
+  INSERT INTO audit (message) VALUES (msg);
+  SELECT LAST_INSERT_ID();
+END$$
+
+CREATE TRIGGER student_ai AFTER INSERT ON student
+FOR EACH ROW
+BEGIN
+  CALL add_audit(CONCAT('student;', NEW.id));
+END$$
+DELIMITER ;
+
+INSERT INTO audit (message) VALUES ('done; really');
@@ -0,0 +1,24 @@
+# Routines without DELIMITER directives, as sent through a driver
+CREATE FUNCTION grade(score INT) RETURNS CHAR(1) DETERMINISTIC
+BEGIN
+  DECLARE result CHAR(1);
+  CASE
+    WHEN score >= 90 THEN SET result = 'A';
+    ELSE SET result = 'B';
+  END CASE;
+  IF score < 0 THEN
+    SET result = '?';
+  END IF;
+  RETURN result;
+END;
+
+CREATE PROCEDURE count_down(IN n INT)
+BEGIN
+  WHILE n > 0 DO
+    SET n = n - 1;
+  END WHILE;
+  SELECT CASE WHEN n = 0 THEN 'zero' ELSE 'other' END AS state;
+END;
+
+CREATE TABLE `order;items` (id INT);
+SELECT 'a\';b' FROM dual;
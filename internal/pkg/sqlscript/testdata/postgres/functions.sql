@@ -0,0 +1,28 @@
+CREATE TABLE audit (id SERIAL PRIMARY KEY, message TEXT);
+
+CREATE OR REPLACE FUNCTION log_student() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO audit (message) VALUES ('student;' || NEW.id);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER student_ai AFTER INSERT ON student
+FOR EACH ROW EXECUTE FUNCTION log_student();
+
+CREATE FUNCTION add_one(n integer) RETURNS integer
+LANGUAGE SQL
+BEGIN ATOMIC
+  SELECT n + 1;
+END;
+
+CREATE PROCEDURE purge_audit()
+LANGUAGE plpgsql
+AS $body$
+BEGIN
+  DELETE FROM audit WHERE message LIKE '%;%';
+END
+$body$;
+
+/* nested /* comment; */ still a comment; */
+SELECT E'it\'s; fine', $$;$$;
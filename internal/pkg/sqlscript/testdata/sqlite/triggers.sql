@@ -0,0 +1,19 @@
+CREATE TABLE audit (id INTEGER PRIMARY KEY, message TEXT);
+
+CREATE TRIGGER student_ai AFTER INSERT ON student
+BEGIN
+  INSERT INTO audit (message) VALUES ('student;' || NEW.id);
+  UPDATE audit SET message = CASE WHEN NEW.id > 10 THEN 'big;' ELSE message END
+  WHERE id = last_insert_rowid();
+END;
+
+CREATE TRIGGER IF NOT EXISTS student_bd BEFORE DELETE ON student
+FOR EACH ROW WHEN OLD.id = 0
+BEGIN
+  SELECT RAISE(ABORT, 'cannot; delete');
+END;
+
+BEGIN;
+INSERT INTO [audit;log] VALUES (1);
+COMMIT;
+-- trailing comment only;
@@ -0,0 +1,31 @@
+CREATE TABLE audit (id INT IDENTITY(1,1) PRIMARY KEY, message NVARCHAR(255));
+SET IDENTITY_INSERT audit ON;
+INSERT INTO audit (id, message) VALUES (1, N'first; row');
+SET IDENTITY_INSERT audit OFF;
+GO
+
+CREATE OR ALTER PROCEDURE add_audit @msg NVARCHAR(255)
+AS
+BEGIN
+  SET NOCOUNT ON;
+  INSERT INTO audit (message) VALUES (@msg);
+END;
+GO
+
+CREATE FUNCTION dbo.grade (@score INT)
+RETURNS CHAR(1)
+AS
+BEGIN
+  RETURN CASE WHEN @score >= 90 THEN 'A' ELSE 'B' END;
+END
+go
+
+CREATE TRIGGER student_ai ON student AFTER INSERT
+AS
+  INSERT INTO audit (message) SELECT N'student;' + CAST(id AS NVARCHAR) FROM inserted;
+  PRINT 'logged';
+GO
+
+SELECT [go;column] FROM audit;
+-- GO inside a comment is not a separator
+SELECT 1 AS go;
@@ -5,6 +5,7 @@ import (
 	"github.com/willbrid/api-gateway-sql/pkg/uuid"
 
 	"context"
+	"time"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
@@ -22,13 +23,16 @@ func NewBatchStatRepo(appDb *gorm.DB, logger zerolog.Logger) *BatchStatRepo {
 	}
 }
 
-func (d *BatchStatRepo) Create(ctx context.Context, targetName string) (*domain.BatchStat, error) {
+func (d *BatchStatRepo) Create(ctx context.Context, targetName string, dataSourceName string) (*domain.BatchStat, error) {
 	uid := uuid.GenerateUID()
 
 	batchStat := domain.BatchStat{
-		ID:         uid,
-		TargetName: targetName,
-		Completed:  false,
+		ID:             uid,
+		TargetName:     targetName,
+		DataSourceName: dataSourceName,
+		Completed:      false,
+		Status:         domain.BatchRunning,
+		HeartbeatAt:    time.Now().Unix(),
 	}
 
 	if err := d.appDb.WithContext(ctx).Create(&batchStat).Error; err != nil {
@@ -41,6 +45,7 @@ func (d *BatchStatRepo) Create(ctx context.Context, targetName string) (*domain.
 
 func (d *BatchStatRepo) UpdateLastCompleted(ctx context.Context, batchStat *domain.BatchStat) error {
 	batchStat.Completed = true
+	batchStat.Status = domain.BatchCompleted
 
 	if err := d.appDb.WithContext(ctx).Save(&batchStat).Error; err != nil {
 		d.logger.Error().Err(err).Msg("failed to update batchStat")
@@ -50,6 +55,89 @@ func (d *BatchStatRepo) UpdateLastCompleted(ctx context.Context, batchStat *doma
 	return nil
 }
 
+// MarkFailed completes a batch stopped before its end
+func (d *BatchStatRepo) MarkFailed(ctx context.Context, batchStat *domain.BatchStat) error {
+	batchStat.Completed = true
+	batchStat.Status = domain.BatchFailed
+
+	err := d.appDb.WithContext(ctx).Model(batchStat).Updates(map[string]any{"completed": true, "status": domain.BatchFailed}).Error
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to mark batchStat failed")
+		return err
+	}
+
+	return nil
+}
+
+// MarkCancelled completes a batch stopped on request, with the last line it processed
+func (d *BatchStatRepo) MarkCancelled(ctx context.Context, batchStat *domain.BatchStat) error {
+	batchStat.Completed = true
+	batchStat.Status = domain.BatchCancelled
+
+	updates := map[string]any{"completed": true, "status": domain.BatchCancelled, "last_line": batchStat.LastLine}
+	if err := d.appDb.WithContext(ctx).Model(batchStat).Updates(updates).Error; err != nil {
+		d.logger.Error().Err(err).Msg("failed to mark batchStat cancelled")
+		return err
+	}
+
+	return nil
+}
+
+// Reopen marks a completed batch as running again, while its failed lines are retried, and counts the retry
+func (d *BatchStatRepo) Reopen(ctx context.Context, batchStat *domain.BatchStat) error {
+	batchStat.Completed = false
+	batchStat.Status = domain.BatchRunning
+	batchStat.HeartbeatAt = time.Now().Unix()
+	batchStat.Retries++
+
+	updates := map[string]any{
+		"completed":    false,
+		"status":       domain.BatchRunning,
+		"heartbeat_at": batchStat.HeartbeatAt,
+		"retries":      batchStat.Retries,
+	}
+	if err := d.appDb.WithContext(ctx).Model(batchStat).Updates(updates).Error; err != nil {
+		d.logger.Error().Err(err).Msg("failed to reopen batchStat")
+		return err
+	}
+
+	return nil
+}
+
+// Heartbeat tells that a running batch is still processed
+func (d *BatchStatRepo) Heartbeat(ctx context.Context, uid string) error {
+	err := d.appDb.WithContext(ctx).Model(&domain.BatchStat{}).Where("id = ?", uid).Update("heartbeat_at", time.Now().Unix()).Error
+	if err != nil {
+		d.logger.Error().Err(err).Str("batch_id", uid).Msg("failed to update batchStat heartbeat")
+		return err
+	}
+
+	return nil
+}
+
+// FailStale marks failed the running batches whose last heartbeat is before the given time, and returns their
+// ids
+func (d *BatchStatRepo) FailStale(ctx context.Context, before time.Time) ([]string, error) {
+	var ids []string
+	err := d.appDb.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.BatchStat{}).
+			Where("completed = ? AND heartbeat_at < ?", false, before.Unix()).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		return tx.Model(&domain.BatchStat{}).Where("id IN ?", ids).
+			Updates(map[string]any{"completed": true, "status": domain.BatchFailed}).Error
+	})
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to mark stale batchStat failed")
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 func (d *BatchStatRepo) AddBlockToBatchStat(ctx context.Context, bs *domain.BatchStat, block *domain.Block) (*domain.Block, error) {
 	if err := d.appDb.WithContext(ctx).Model(bs).Association("Blocks").Append(block); err != nil {
 		d.logger.Error().Err(err).Msg("failed to associate block to batchStat")
@@ -75,12 +163,34 @@ func (d *BatchStatRepo) FindById(ctx context.Context, uid string) (*domain.Batch
 	return &batch, nil
 }
 
-func (d *BatchStatRepo) CountUncompletedBatchStat(ctx context.Context) (int64, error) {
+// FindWithFailures returns a batchStat with its blocks and their failure ranges
+func (d *BatchStatRepo) FindWithFailures(ctx context.Context, uid string) (*domain.BatchStat, error) {
+	batch := domain.BatchStat{}
+
+	if err := d.appDb.WithContext(ctx).Preload("Blocks.FailureRanges").First(&batch, "id = ?", uid).Error; err != nil {
+		d.logger.Error().Err(err).Str("batch_id", uid).Msg("failed to find batchStat with its failures")
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// CountRunningByTarget returns the number of running batches of a target
+func (d *BatchStatRepo) CountRunningByTarget(ctx context.Context, targetName string) (int64, error) {
+	return d.countRunning(ctx, "target_name = ?", targetName)
+}
+
+// CountRunningByDataSource returns the number of running batches of the targets of a datasource
+func (d *BatchStatRepo) CountRunningByDataSource(ctx context.Context, dataSourceName string) (int64, error) {
+	return d.countRunning(ctx, "data_source_name = ?", dataSourceName)
+}
+
+func (d *BatchStatRepo) countRunning(ctx context.Context, scope string, name string) (int64, error) {
 	var total int64
 
-	err := d.appDb.WithContext(ctx).Model(&domain.BatchStat{}).Where("Completed = ?", false).Limit(1).Count(&total).Error
+	err := d.appDb.WithContext(ctx).Model(&domain.BatchStat{}).Where("completed = ?", false).Where(scope, name).Count(&total).Error
 	if err != nil {
-		d.logger.Error().Err(err).Msg("failed to count uncompleted batchStat")
+		d.logger.Error().Err(err).Str("scope", name).Msg("failed to count running batchStat")
 		return 0, err
 	}
 
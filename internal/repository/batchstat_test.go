@@ -0,0 +1,80 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/domain"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatchStatRepo_RunningAndStaleBatches(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	appDb := newTestAppDatabase(t)
+	repo := repository.NewBatchStatRepo(appDb, logging.InitLogger())
+
+	stuck, err := repo.Create(ctx, "insert_batch_school", "school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := appDb.Model(stuck).Update("heartbeat_at", time.Now().Add(-time.Hour).Unix()).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.Create(ctx, "insert_batch_student", "school"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if running, err := repo.CountRunningByTarget(ctx, "insert_batch_school"); err != nil || running != 1 {
+		t.Errorf("got %d running batches of the target (%v), want 1", running, err)
+	}
+	if running, err := repo.CountRunningByDataSource(ctx, "school"); err != nil || running != 2 {
+		t.Errorf("got %d running batches of the datasource (%v), want 2", running, err)
+	}
+
+	failed, err := repo.FailStale(ctx, time.Now().Add(-10*time.Minute))
+	if err != nil || len(failed) != 1 || failed[0] != stuck.ID {
+		t.Fatalf("got stale batches %v (%v), want [%s]", failed, err, stuck.ID)
+	}
+
+	found, err := repo.FindById(ctx, stuck.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !found.Completed || found.Status != domain.BatchFailed {
+		t.Errorf("got completed %v with status %s, want a failed batch", found.Completed, found.Status)
+	}
+	if running, err := repo.CountRunningByDataSource(ctx, "school"); err != nil || running != 1 {
+		t.Errorf("got %d running batches of the datasource (%v), want 1", running, err)
+	}
+}
+
+func TestBatchStatRepo_MarkCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewBatchStatRepo(newTestAppDatabase(t), logging.InitLogger())
+
+	batchStat, err := repo.Create(ctx, "insert_batch_school", "school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	batchStat.LastLine = 40
+	if err := repo.MarkCancelled(ctx, batchStat); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found, err := repo.FindById(ctx, batchStat.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !found.Completed || found.Status != domain.BatchCancelled || found.LastLine != 40 {
+		t.Errorf("got completed %v with status %s at line %d, want a batch cancelled at line 40", found.Completed, found.Status, found.LastLine)
+	}
+	if running, err := repo.CountRunningByTarget(ctx, "insert_batch_school"); err != nil || running != 0 {
+		t.Errorf("got %d running batches of the target (%v), want 0", running, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/willbrid/api-gateway-sql/internal/domain"
 
 	"context"
+	"slices"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
@@ -35,6 +36,88 @@ func (b *BlockRepo) Update(ctx context.Context, block *domain.Block, failureRang
 	return b.appDb.WithContext(ctx).Save(block).Error
 }
 
+// ResolveFailure removes a failure range, and the rejected rows of its lines, when they succeeded on a retry,
+// the failed batch of the block becoming a successful one
+func (b *BlockRepo) ResolveFailure(ctx context.Context, block *domain.Block, failureRange *domain.FailureRange) error {
+	return b.appDb.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(failureRange).Error; err != nil {
+			b.logger.Error().Err(err).Msg("failed to delete failure range")
+			return err
+		}
+
+		err := tx.Where("block_id = ? AND line BETWEEN ? AND ?", block.ID, failureRange.StartLine, failureRange.EndLine).Delete(&domain.RejectedRow{}).Error
+		if err != nil {
+			b.logger.Error().Err(err).Msg("failed to delete rejected rows")
+			return err
+		}
+
+		block.FailureCount = max(block.FailureCount-1, 0)
+		block.SuccessCount = block.SuccessCount + 1
+		block.FailureRanges = slices.DeleteFunc(block.FailureRanges, func(fr domain.FailureRange) bool {
+			return fr.ID == failureRange.ID
+		})
+
+		return tx.Model(block).Updates(map[string]any{"failure_count": block.FailureCount, "success_count": block.SuccessCount}).Error
+	})
+}
+
+// Reject records the rejected rows of a failed batch of a block, with a failure range for each run of
+// consecutive rejected lines
+func (b *BlockRepo) Reject(ctx context.Context, block *domain.Block, rejects []*domain.RejectedRow) error {
+	return b.appDb.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var failureRanges []*domain.FailureRange
+		for _, reject := range rejects {
+			reject.BlockID, reject.BatchStatID = block.ID, block.BatchStatID
+
+			if last := len(failureRanges) - 1; last >= 0 && failureRanges[last].EndLine == reject.Line-1 {
+				failureRanges[last].EndLine = reject.Line
+			} else {
+				failureRanges = append(failureRanges, domain.NewFailureRange(reject.Line, reject.Line))
+			}
+		}
+		if len(rejects) == 0 {
+			return nil
+		}
+
+		if err := tx.Create(rejects).Error; err != nil {
+			b.logger.Error().Err(err).Msg("failed to create rejected rows")
+			return err
+		}
+		if err := tx.Model(block).Association("FailureRanges").Append(failureRanges); err != nil {
+			b.logger.Error().Err(err).Msg("failed to update block")
+			return err
+		}
+
+		block.FailureCount = block.FailureCount + len(failureRanges)
+		return tx.Model(block).Update("failure_count", block.FailureCount).Error
+	})
+}
+
+// FindRejectsByBatchStatID returns the rejected rows of a batch, ordered by line
+func (b *BlockRepo) FindRejectsByBatchStatID(ctx context.Context, batchStatId string) ([]*domain.RejectedRow, error) {
+	var rejects []*domain.RejectedRow
+
+	if err := b.appDb.WithContext(ctx).Where("batch_stat_id = ?", batchStatId).Order("line").Find(&rejects).Error; err != nil {
+		b.logger.Error().Err(err).Str("batch_stat_id", batchStatId).Msg("failed to fetch rejected rows for specific batch")
+		return nil, err
+	}
+
+	return rejects, nil
+}
+
+// CountFailuresByBatchStatID returns the number of failure ranges of the blocks of a batch
+func (b *BlockRepo) CountFailuresByBatchStatID(ctx context.Context, batchStatId string) (int64, error) {
+	var total int64
+
+	blockIds := b.appDb.Model(&domain.Block{}).Select("id").Where("batch_stat_id = ?", batchStatId)
+	if err := b.appDb.WithContext(ctx).Model(&domain.FailureRange{}).Where("block_id IN (?)", blockIds).Count(&total).Error; err != nil {
+		b.logger.Error().Err(err).Str("batch_stat_id", batchStatId).Msg("failed to count failure ranges for specific batch")
+		return 0, err
+	}
+
+	return total, nil
+}
+
 func (b *BlockRepo) FindAllByBatchStatID(ctx context.Context, batchStatId string, offset, limit int) ([]*domain.Block, int64, error) {
 	var blocks []*domain.Block
 	var total int64
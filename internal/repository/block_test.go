@@ -0,0 +1,140 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/domain"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestAppDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	appDb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "app.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open app database: %v", err)
+	}
+	if err := appDb.AutoMigrate(&domain.BatchStat{}, &domain.Block{}, &domain.FailureRange{}, &domain.RejectedRow{}); err != nil {
+		t.Fatalf("failed to migrate app database: %v", err)
+	}
+	t.Cleanup(func() {
+		if cnx, err := appDb.DB(); err == nil {
+			_ = cnx.Close()
+		}
+	})
+
+	return appDb
+}
+
+func TestBlockRepo_ResolveFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	appDb := newTestAppDatabase(t)
+	batchStatRepo := repository.NewBatchStatRepo(appDb, logging.InitLogger())
+	blockRepo := repository.NewBlockRepo(appDb, logging.InitLogger())
+
+	batchStat, err := batchStatRepo.Create(ctx, "insert_batch_school", "school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	block, err := batchStatRepo.AddBlockToBatchStat(ctx, batchStat, domain.NewBlock(1, 20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, failureRange := range []*domain.FailureRange{domain.NewFailureRange(0, 10), domain.NewFailureRange(10, 20)} {
+		if err := blockRepo.Update(ctx, block, failureRange, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	found, err := batchStatRepo.FindWithFailures(ctx, batchStat.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found.Blocks) != 1 || len(found.Blocks[0].FailureRanges) != 2 {
+		t.Fatalf("got blocks %+v, want 1 block with 2 failure ranges", found.Blocks)
+	}
+
+	foundBlock := &found.Blocks[0]
+	if err := blockRepo.ResolveFailure(ctx, foundBlock, &foundBlock.FailureRanges[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failures, err := blockRepo.CountFailuresByBatchStatID(ctx, batchStat.ID)
+	if err != nil || failures != 1 {
+		t.Errorf("got %d failure ranges (%v), want 1", failures, err)
+	}
+
+	updated, err := blockRepo.FindById(ctx, block.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.FailureCount != 1 || updated.SuccessCount != 1 {
+		t.Errorf("got %d failures and %d successes, want 1 and 1", updated.FailureCount, updated.SuccessCount)
+	}
+}
+
+func TestBlockRepo_Reject(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	appDb := newTestAppDatabase(t)
+	batchStatRepo := repository.NewBatchStatRepo(appDb, logging.InitLogger())
+	blockRepo := repository.NewBlockRepo(appDb, logging.InitLogger())
+
+	batchStat, err := batchStatRepo.Create(ctx, "insert_batch_school", "school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	block, err := batchStatRepo.AddBlockToBatchStat(ctx, batchStat, domain.NewBlock(1, 20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rejects := []*domain.RejectedRow{
+		domain.NewRejectedRow(3, `["a","1"]`, "UNIQUE constraint failed"),
+		domain.NewRejectedRow(4, `["b","2"]`, "UNIQUE constraint failed"),
+		domain.NewRejectedRow(9, `["c","3"]`, "NOT NULL constraint failed"),
+	}
+	if err := blockRepo.Reject(ctx, block, rejects); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found, err := batchStatRepo.FindWithFailures(ctx, batchStat.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	foundBlock := &found.Blocks[0]
+	if foundBlock.FailureCount != 2 || len(foundBlock.FailureRanges) != 2 {
+		t.Fatalf("got %d failures and ranges %+v, want 2 ranges", foundBlock.FailureCount, foundBlock.FailureRanges)
+	}
+
+	var lines [][2]int
+	for _, failureRange := range foundBlock.FailureRanges {
+		lines = append(lines, [2]int{failureRange.StartLine, failureRange.EndLine})
+	}
+	if !slices.Contains(lines, [2]int{3, 4}) || !slices.Contains(lines, [2]int{9, 9}) {
+		t.Errorf("got failure ranges %v, want 3-4 and 9-9", lines)
+	}
+
+	rangeIdx := slices.IndexFunc(foundBlock.FailureRanges, func(fr domain.FailureRange) bool { return fr.StartLine == 3 })
+	if err := blockRepo.ResolveFailure(ctx, foundBlock, &foundBlock.FailureRanges[rangeIdx]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remaining, err := blockRepo.FindRejectsByBatchStatID(ctx, batchStat.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Line != 9 || remaining[0].Error != "NOT NULL constraint failed" {
+		t.Errorf("got rejected rows %+v, want line 9 only", remaining)
+	}
+}
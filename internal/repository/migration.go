@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/domain"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/migration"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlscript"
+
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type MigrationRepo struct {
+	logger zerolog.Logger
+}
+
+func NewMigrationRepo(logger zerolog.Logger) *MigrationRepo {
+	return &MigrationRepo{
+		logger: logger.With().Str("layer", "repository").Str("component", "migrationrepo").Logger(),
+	}
+}
+
+// FindApplied creates the migration history table of the datasource if needed and returns its content by version
+func (r *MigrationRepo) FindApplied(ctx context.Context, db *gorm.DB) ([]domain.SchemaMigration, error) {
+	if err := r.ensureHistoryTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	var applied []domain.SchemaMigration
+	if err := db.WithContext(ctx).Order("version").Find(&applied).Error; err != nil {
+		r.logger.Error().Err(err).Msg("failed to list applied migrations")
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Apply runs the up script of a migration and records it in the history table.
+// Both happen in one transaction when the dialect rolls back DDL statements.
+func (r *MigrationRepo) Apply(ctx context.Context, db *gorm.DB, m migration.Migration) error {
+	record := &domain.SchemaMigration{Version: m.Version, Name: m.Name, Checksum: m.Checksum}
+
+	return r.run(ctx, db, m.Up, func(tx *gorm.DB) error {
+		return tx.Create(record).Error
+	})
+}
+
+// Revert runs the down script of a migration and removes it from the history table
+func (r *MigrationRepo) Revert(ctx context.Context, db *gorm.DB, m migration.Migration) error {
+	return r.run(ctx, db, m.Down, func(tx *gorm.DB) error {
+		return tx.Delete(&domain.SchemaMigration{}, m.Version).Error
+	})
+}
+
+func (r *MigrationRepo) run(ctx context.Context, db *gorm.DB, script string, record func(tx *gorm.DB) error) error {
+	if err := r.ensureHistoryTable(ctx, db); err != nil {
+		return err
+	}
+
+	dialect := db.Dialector.Name()
+	statements, err := sqlscript.Split(dialect, script)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to split migration script")
+		return err
+	}
+
+	execute := func(tx *gorm.DB) error {
+		for idx, statement := range statements {
+			if _, err := tx.Statement.ConnPool.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("statement %d: %w", idx+1, err)
+			}
+		}
+
+		return record(tx)
+	}
+
+	if migration.Transactional(dialect) {
+		err = db.WithContext(ctx).Transaction(execute)
+	} else {
+		err = execute(db.WithContext(ctx))
+	}
+
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to run migration script")
+		return err
+	}
+
+	return nil
+}
+
+func (r *MigrationRepo) ensureHistoryTable(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).AutoMigrate(&domain.SchemaMigration{}); err != nil {
+		r.logger.Error().Err(err).Msg("failed to create migration history table")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/pkg/migration"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"testing"
+)
+
+func TestMigrationRepo_ApplyAndRevert(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewMigrationRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "school").Get("school")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	migrations, err := migration.Parse(map[string]string{
+		"001_create_student.up.sql":   "create table student (id integer primary key);\ncreate index student_id on student (id);",
+		"001_create_student.down.sql": "drop table student;",
+		"002_broken.up.sql":           "create table teacher (id integer primary key);\ninsert into missing values (1);",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Apply(ctx, cnx, migrations[0]); err != nil {
+		t.Fatalf("failed to apply migration: %v", err)
+	}
+
+	if err := repo.Apply(ctx, cnx, migrations[1]); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+
+	applied, err := repo.FindApplied(ctx, cnx)
+	if err != nil {
+		t.Fatalf("failed to list applied migrations: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 1 || applied[0].Checksum != migrations[0].Checksum {
+		t.Fatalf("got applied %+v, want only version 1", applied)
+	}
+
+	// the failed migration is rolled back as a whole
+	if cnx.Migrator().HasTable("teacher") {
+		t.Error("table teacher of the failed migration should not exist")
+	}
+
+	if err := repo.Revert(ctx, cnx, migrations[0]); err != nil {
+		t.Fatalf("failed to revert migration: %v", err)
+	}
+
+	applied, err = repo.FindApplied(ctx, cnx)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("got applied %+v, %v, want none", applied, err)
+	}
+	if cnx.Migrator().HasTable("student") {
+		t.Error("table student should be dropped")
+	}
+}
@@ -0,0 +1,88 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/dto/paginator"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"testing"
+)
+
+func TestSQLQueryRepo_ExecutePageQueries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "school").Get("school")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table student (id integer primary key, name text)",
+		"with recursive seq(n) as (select 1 union all select n + 1 from seq where n < 25) insert into student select n, 'student ' || n from seq",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	query := "select id, name from student where id > {{min}} order by id;"
+	params := map[string]any{"min": 0}
+
+	count, err := repo.Execute(ctx, cnx, sqlqueryhelper.CountQuery(sqllexer.SQLiteDialect, query), sqlqueryhelper.QueryMode, params)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if total := count.Rows[0]["total"]; total != int64(25) {
+		t.Fatalf("got total %v, want 25", total)
+	}
+
+	page := sqlqueryhelper.Page{Limit: 10, Offset: 20}
+	result, err := repo.Execute(ctx, cnx, sqlqueryhelper.PageQuery(sqllexer.SQLiteDialect, query, page), sqlqueryhelper.QueryMode, params)
+	if err != nil {
+		t.Fatalf("failed to get offset page: %v", err)
+	}
+	if len(result.Rows) != 5 || result.Rows[0]["id"] != int64(21) {
+		t.Fatalf("got offset page %v, want ids 21 to 25", result.Rows)
+	}
+
+	// walk the keyset pages in descending order
+	var ids []int64
+	page = sqlqueryhelper.Page{Limit: 10, CursorColumn: "id", Descending: true}
+	pageParams := map[string]any{"min": 0}
+	for {
+		result, err := repo.Execute(ctx, cnx, sqlqueryhelper.PageQuery(sqllexer.SQLiteDialect, query, page), sqlqueryhelper.QueryMode, pageParams)
+		if err != nil {
+			t.Fatalf("failed to get keyset page: %v", err)
+		}
+		if len(result.Rows) == 0 {
+			break
+		}
+
+		for _, row := range result.Rows {
+			ids = append(ids, row["id"].(int64))
+		}
+
+		token, err := paginator.EncodeCursor(result.Rows[len(result.Rows)-1]["id"])
+		if err != nil {
+			t.Fatalf("failed to encode cursor: %v", err)
+		}
+		cursor, err := paginator.DecodeCursor(token)
+		if err != nil {
+			t.Fatalf("failed to decode cursor: %v", err)
+		}
+		pageParams[sqlqueryhelper.CursorParam] = cursor
+		page.AfterCursor = true
+	}
+
+	if len(ids) != 25 {
+		t.Fatalf("got %d rows over the keyset pages, want 25", len(ids))
+	}
+	for idx, id := range ids {
+		if id != int64(25-idx) {
+			t.Fatalf("got id %d at position %d, want %d", id, idx, 25-idx)
+		}
+	}
+}
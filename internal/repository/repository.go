@@ -2,39 +2,62 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/willbrid/api-gateway-sql/internal/domain"
 	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/migration"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
 )
 
 type ISQLQueryRepo interface {
-	Execute(ctx context.Context, query string, params map[string]any) (*dto.SQLQueryOutput, error)
-	ExecuteBatch(ctx context.Context, query string, params []map[string]any) error
-	ExecuteInit(ctx context.Context, sqlQueries []string) error
+	Execute(ctx context.Context, db *gorm.DB, query string, mode string, params map[string]any) (*dto.SQLQueryOutput, error)
+	Stream(ctx context.Context, db *gorm.DB, query string, params map[string]any, writer dto.RowWriter) (int64, error)
+	ExecuteBatch(ctx context.Context, db *gorm.DB, query string, params []map[string]any) error
+	ExecuteSteps(ctx context.Context, db *gorm.DB, steps []dto.SQLStep, params map[string]any) ([]dto.SQLStepOutput, error)
+	CallProcedure(ctx context.Context, db *gorm.DB, procedure dto.ProcedureCall, params map[string]any) (*dto.SQLQueryOutput, error)
+	ExecuteInit(ctx context.Context, db *gorm.DB, sqlQueries []string) error
 }
 
 type IBatchStat interface {
-	Create(ctx context.Context, targetName string) (*domain.BatchStat, error)
+	Create(ctx context.Context, targetName string, dataSourceName string) (*domain.BatchStat, error)
 	UpdateLastCompleted(ctx context.Context, batchStat *domain.BatchStat) error
+	MarkFailed(ctx context.Context, batchStat *domain.BatchStat) error
+	MarkCancelled(ctx context.Context, batchStat *domain.BatchStat) error
+	Reopen(ctx context.Context, batchStat *domain.BatchStat) error
+	Heartbeat(ctx context.Context, uid string) error
+	FailStale(ctx context.Context, before time.Time) ([]string, error)
 	AddBlockToBatchStat(ctx context.Context, bs *domain.BatchStat, block *domain.Block) (*domain.Block, error)
 	FindAll(ctx context.Context, offset, limit int) ([]*domain.BatchStat, int64, error)
 	FindById(ctx context.Context, uid string) (*domain.BatchStat, error)
-	CountUncompletedBatchStat(ctx context.Context) (int64, error)
+	FindWithFailures(ctx context.Context, uid string) (*domain.BatchStat, error)
+	CountRunningByTarget(ctx context.Context, targetName string) (int64, error)
+	CountRunningByDataSource(ctx context.Context, dataSourceName string) (int64, error)
 }
 
 type IBlock interface {
 	Update(ctx context.Context, block *domain.Block, failureRange *domain.FailureRange, isSuccess bool) error
+	ResolveFailure(ctx context.Context, block *domain.Block, failureRange *domain.FailureRange) error
+	Reject(ctx context.Context, block *domain.Block, rejects []*domain.RejectedRow) error
+	FindRejectsByBatchStatID(ctx context.Context, batchStatId string) ([]*domain.RejectedRow, error)
+	CountFailuresByBatchStatID(ctx context.Context, batchStatId string) (int64, error)
 	FindAllByBatchStatID(ctx context.Context, batchStatId string, offset, limit int) ([]*domain.Block, int64, error)
 	FindById(ctx context.Context, uid string) (*domain.Block, error)
 }
 
+type IMigration interface {
+	FindApplied(ctx context.Context, db *gorm.DB) ([]domain.SchemaMigration, error)
+	Apply(ctx context.Context, db *gorm.DB, m migration.Migration) error
+	Revert(ctx context.Context, db *gorm.DB, m migration.Migration) error
+}
+
 type Repositories struct {
 	ISQLQueryRepo ISQLQueryRepo
 	IBatchStat    IBatchStat
 	IBlock        IBlock
+	IMigration    IMigration
 }
 
 func NewRepositories(appDb *gorm.DB, logger zerolog.Logger) *Repositories {
@@ -42,5 +65,6 @@ func NewRepositories(appDb *gorm.DB, logger zerolog.Logger) *Repositories {
 		ISQLQueryRepo: NewSQLQueryRepo(logger),
 		IBatchStat:    NewBatchStatRepo(appDb, logger),
 		IBlock:        NewBlockRepo(appDb, logger),
+		IMigration:    NewMigrationRepo(logger),
 	}
 }
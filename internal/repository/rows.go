@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/columntype"
+
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+// scanRows reads every row of a result set into maps keyed by column name
+func scanRows(rows *sql.Rows, mapping typeMapping) ([]map[string]any, error) {
+	var result []map[string]any
+
+	err := forEachRow(rows, mapping, func(columns []string, values []any) error {
+		row := make(map[string]any, len(columns))
+		for idx, column := range columns {
+			row[column] = values[idx]
+		}
+		result = append(result, row)
+
+		return nil
+	})
+
+	return result, err
+}
+
+// scanResultSets reads every result set of a query, such as the result sets of a stored procedure.
+// Result sets without columns, like the status ending a mysql CALL, are skipped.
+func scanResultSets(rows *sql.Rows, mapping typeMapping) ([][]map[string]any, error) {
+	var resultSets [][]map[string]any
+
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(columns) > 0 {
+			resultSet, err := scanRows(rows, mapping)
+			if err != nil {
+				return nil, err
+			}
+			if resultSet == nil {
+				resultSet = []map[string]any{}
+			}
+			resultSets = append(resultSets, resultSet)
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	return resultSets, rows.Err()
+}
+
+// streamRows sends the columns then each row of a result set to a writer, without keeping them.
+// It returns the number of rows written.
+func streamRows(rows *sql.Rows, mapping typeMapping, writer dto.RowWriter) (int64, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writer.WriteColumns(columns); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = forEachRow(rows, mapping, func(_ []string, values []any) error {
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+		count++
+
+		return nil
+	})
+
+	return count, err
+}
+
+// forEachRow scans each row of a result set and calls fn with the column names and the row values,
+// converted to the json types of their columns
+func forEachRow(rows *sql.Rows, mapping typeMapping, fn func(columns []string, values []any) error) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	jsonTypes := mapping.jsonTypes(columnTypes)
+	dests := prepareValues(columnTypes, jsonTypes)
+	for rows.Next() {
+		if err := rows.Scan(dests...); err != nil {
+			return err
+		}
+
+		values := make([]any, len(columns))
+		for idx := range columns {
+			values[idx] = columntype.Normalize(mapping.dialect, jsonTypes[idx], scannedValue(dests[idx]))
+		}
+
+		if err := fn(columns, values); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// prepareValues allocates a scan destination for each column. Converted columns receive the driver value, so that
+// decimals are not rounded to a float, other columns receive a value of the driver scan type.
+func prepareValues(columnTypes []*sql.ColumnType, jsonTypes []string) []any {
+	values := make([]any, len(columnTypes))
+	for idx, columnType := range columnTypes {
+		if scanType := columnType.ScanType(); scanType != nil && jsonTypes[idx] == columntype.Raw {
+			values[idx] = reflect.New(reflect.PointerTo(scanType)).Interface()
+		} else {
+			values[idx] = new(any)
+		}
+	}
+
+	return values
+}
+
+// scannedValue dereferences a scan destination, NULL becomes nil and raw bytes become a string
+func scannedValue(dest any) any {
+	// drivers may report a pointer scan type, such as *interface{} for computed sqlite columns
+	reflectValue := reflect.ValueOf(dest)
+	for reflectValue.Kind() == reflect.Pointer || reflectValue.Kind() == reflect.Interface {
+		if reflectValue.IsNil() {
+			return nil
+		}
+		reflectValue = reflectValue.Elem()
+	}
+	if !reflectValue.IsValid() {
+		return nil
+	}
+
+	value := reflectValue.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		value, _ = valuer.Value()
+	} else if rawBytes, ok := value.(sql.RawBytes); ok {
+		value = string(rawBytes)
+	}
+
+	return value
+}
+
+type columnTypesKey struct{}
+
+// WithColumnTypes returns a context whose queries convert the values of the given columns to their json type
+// (one of the columntype types), instead of the json type of their database type
+func WithColumnTypes(ctx context.Context, columnTypes map[string]string) context.Context {
+	return context.WithValue(ctx, columnTypesKey{}, columnTypes)
+}
+
+// typeMapping tells the json type of the values of each column, from the database type of the column
+// unless it is overridden
+type typeMapping struct {
+	dialect   string
+	overrides map[string]string
+}
+
+func newTypeMapping(ctx context.Context, dialect string) typeMapping {
+	overrides, _ := ctx.Value(columnTypesKey{}).(map[string]string)
+	return typeMapping{dialect: dialect, overrides: overrides}
+}
+
+func (m typeMapping) jsonTypes(columnTypes []*sql.ColumnType) []string {
+	jsonTypes := make([]string, len(columnTypes))
+	for idx, columnType := range columnTypes {
+		if jsonType, exists := m.overrides[columnType.Name()]; exists {
+			jsonTypes[idx] = jsonType
+		} else {
+			jsonTypes[idx] = columntype.Of(columnType.DatabaseTypeName())
+		}
+	}
+
+	return jsonTypes
+}
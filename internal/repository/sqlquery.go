@@ -2,17 +2,22 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
 
 	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqllexer"
 	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
 )
 
+// SQLQueryRepo executes queries on external datasources.
+// It holds no connection: each call receives the connection it runs on, so a single instance can be shared across goroutines.
 type SQLQueryRepo struct {
-	db     *gorm.DB
 	logger zerolog.Logger
 }
 
@@ -20,24 +25,12 @@ func NewSQLQueryRepo(logger zerolog.Logger) *SQLQueryRepo {
 	return &SQLQueryRepo{logger: logger.With().Str("layer", "repository").Str("component", "sqlqueryrepo").Logger()}
 }
 
-func (r *SQLQueryRepo) SetDB(db *gorm.DB) {
-	r.db = db
-}
-
-func (r *SQLQueryRepo) CloseDB() {
-	if cnx, err := r.db.DB(); err == nil {
-		_ = cnx.Close()
-	} else {
-		r.logger.Error().Err(err).Msg("unable to close database session")
-	}
-}
-
-func (r *SQLQueryRepo) ExecuteInit(ctx context.Context, sqlQueries []string) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+func (r *SQLQueryRepo) ExecuteInit(ctx context.Context, db *gorm.DB, sqlQueries []string) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for _, sqlQuery := range sqlQueries {
 			query := strings.TrimSpace(sqlQuery)
 			if query != "" {
-				if err := tx.Exec(query).Error; err != nil {
+				if _, err := tx.Statement.ConnPool.ExecContext(ctx, query); err != nil {
 					r.logger.Error().Err(err).Msg("failed to execute transaction for schema creation")
 					return err
 				}
@@ -48,23 +41,36 @@ func (r *SQLQueryRepo) ExecuteInit(ctx context.Context, sqlQueries []string) err
 	})
 }
 
-func (r *SQLQueryRepo) Execute(ctx context.Context, query string, params map[string]any) (*dto.SQLQueryOutput, error) {
-	parsedQuery, parsedParams := sqlqueryhelper.TransformQuery(query, params)
+// Execute runs a target query, its {{param}} are bound with the placeholders of the connection dialect.
+// The mode (query or exec) tells whether the query returns rows, it is detected from the query when empty.
+func (r *SQLQueryRepo) Execute(ctx context.Context, db *gorm.DB, query string, mode string, params map[string]any) (*dto.SQLQueryOutput, error) {
+	dialect := db.Dialector.Name()
+	parsedQuery, parsedParams, err := sqlqueryhelper.TransformQuery(dialect, query, params)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to bind query params")
+		return nil, err
+	}
 
-	if sqlqueryhelper.IsSelectQuery(parsedQuery) {
-		return r.executeSelect(ctx, parsedQuery, parsedParams)
+	if sqlqueryhelper.IsQueryMode(mode, dialect, parsedQuery) {
+		return r.executeSelect(ctx, db, parsedQuery, parsedParams)
 	}
 
-	return r.executeWrite(ctx, parsedQuery, parsedParams)
+	return r.executeWrite(ctx, db, parsedQuery, parsedParams)
 }
 
-func (r *SQLQueryRepo) executeSelect(ctx context.Context, query string, params []any) (*dto.SQLQueryOutput, error) {
-	var rows []map[string]any
-
-	if err := r.db.WithContext(ctx).Raw(query, params...).Scan(&rows).Error; err != nil {
+func (r *SQLQueryRepo) executeSelect(ctx context.Context, db *gorm.DB, query string, params []any) (*dto.SQLQueryOutput, error) {
+	result, err := db.WithContext(ctx).Statement.ConnPool.QueryContext(ctx, query, params...)
+	if err != nil {
 		r.logger.Error().Err(err).Str("query", query).Msg("failed to execute select query")
 		return nil, err
 	}
+	defer result.Close()
+
+	rows, err := scanRows(result, newTypeMapping(ctx, db.Dialector.Name()))
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to read select query rows")
+		return nil, err
+	}
 
 	return &dto.SQLQueryOutput{
 		Rows:         rows,
@@ -72,32 +78,323 @@ func (r *SQLQueryRepo) executeSelect(ctx context.Context, query string, params [
 	}, nil
 }
 
-func (r *SQLQueryRepo) executeWrite(ctx context.Context, query string, params []any) (*dto.SQLQueryOutput, error) {
-	tx := r.db.WithContext(ctx).Exec(query, params...)
+// Stream runs a target query returning rows and sends them one by one to the writer.
+// Cancelling the context aborts the query. It returns the number of rows written.
+func (r *SQLQueryRepo) Stream(ctx context.Context, db *gorm.DB, query string, params map[string]any, writer dto.RowWriter) (int64, error) {
+	parsedQuery, parsedParams, err := sqlqueryhelper.TransformQuery(db.Dialector.Name(), query, params)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to bind streamed query params")
+		return 0, err
+	}
+
+	result, err := db.WithContext(ctx).Statement.ConnPool.QueryContext(ctx, parsedQuery, parsedParams...)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", parsedQuery).Msg("failed to execute streamed query")
+		return 0, err
+	}
+	defer result.Close()
+
+	count, err := streamRows(result, newTypeMapping(ctx, db.Dialector.Name()), writer)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", parsedQuery).Int64("rows", count).Msg("failed to stream query rows")
+		return count, err
+	}
+
+	return count, nil
+}
+
+func (r *SQLQueryRepo) executeWrite(ctx context.Context, db *gorm.DB, query string, params []any) (*dto.SQLQueryOutput, error) {
+	result, err := db.WithContext(ctx).Statement.ConnPool.ExecContext(ctx, query, params...)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to execute write query")
+		return nil, err
+	}
 
-	if tx.Error != nil {
-		r.logger.Error().Err(tx.Error).Str("query", query).Msg("failed to execute write query")
-		return nil, tx.Error
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to get write query affected rows")
+		return nil, err
 	}
 
 	return &dto.SQLQueryOutput{
 		Rows:         nil,
-		AffectedRows: tx.RowsAffected,
+		AffectedRows: affectedRows,
 	}, nil
 }
 
-func (r *SQLQueryRepo) ExecuteBatch(ctx context.Context, query string, params []map[string]any) error {
-	cnx := r.db
+func (r *SQLQueryRepo) ExecuteBatch(ctx context.Context, db *gorm.DB, query string, params []map[string]any) error {
+	dialect := db.Dialector.Name()
 
-	return cnx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for _, param := range params {
-			parsedQuery, parsedParams := sqlqueryhelper.TransformQuery(query, param)
-			if err := tx.Exec(parsedQuery, parsedParams...).Error; err != nil {
-				r.logger.Error().Err(tx.Error).Str("query", parsedQuery).Msg("failed to execute batch query")
+			renderedQuery, err := sqlqueryhelper.RenderTemplate(query, param)
+			if err != nil {
+				r.logger.Error().Err(err).Str("query", query).Msg("failed to render batch query")
 				return err
 			}
+
+			parsedQuery, parsedParams, err := sqlqueryhelper.TransformQuery(dialect, renderedQuery, param)
+			if err != nil {
+				r.logger.Error().Err(err).Str("query", query).Msg("failed to bind batch query params")
+				return err
+			}
+			if _, err := tx.Statement.ConnPool.ExecContext(ctx, parsedQuery, parsedParams...); err != nil {
+				r.logger.Error().Err(err).Str("query", parsedQuery).Msg("failed to execute batch query")
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ExecuteSteps runs statements in order in a single transaction, any failure rolls back every statement and is
+// returned as a *dto.StepError. A statement binds its own params along with the shared params.
+// The first row returned by a statement is bound to the next ones as {{name.column}} params, a column is nil
+// without rows. A write statement binds {{name.last_insert_id}} when the driver reports it (mysql, sqlite).
+func (r *SQLQueryRepo) ExecuteSteps(ctx context.Context, db *gorm.DB, steps []dto.SQLStep, params map[string]any) ([]dto.SQLStepOutput, error) {
+	dialect := db.Dialector.Name()
+	stepParams := make(map[string]any, len(params))
+	maps.Copy(stepParams, params)
+
+	outputs := make([]dto.SQLStepOutput, 0, len(steps))
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for idx, step := range steps {
+			boundParams := stepParams
+			if len(step.Params) > 0 {
+				boundParams = make(map[string]any, len(stepParams)+len(step.Params))
+				maps.Copy(boundParams, stepParams)
+				maps.Copy(boundParams, step.Params)
+			}
+
+			output, err := r.executeStep(ctx, tx, dialect, step, boundParams, stepParams)
+			if err != nil {
+				return &dto.StepError{Index: idx, Name: step.Name, Err: err}
+			}
+			outputs = append(outputs, *output)
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// executeStep runs a statement with the params, its first row and its last insert id are added to the results
+func (r *SQLQueryRepo) executeStep(ctx context.Context, tx *gorm.DB, dialect string, step dto.SQLStep, params map[string]any, results map[string]any) (*dto.SQLStepOutput, error) {
+	renderedQuery, err := sqlqueryhelper.RenderTemplate(step.Query, params)
+	if err != nil {
+		r.logger.Error().Err(err).Str("step", step.Name).Msg("failed to render step query")
+		return nil, err
+	}
+
+	query, queryParams, err := sqlqueryhelper.TransformQuery(dialect, renderedQuery, params)
+	if err != nil {
+		r.logger.Error().Err(err).Str("step", step.Name).Msg("failed to bind step query params")
+		return nil, err
+	}
+	output := &dto.SQLStepOutput{Name: step.Name}
+
+	if !sqlqueryhelper.IsQueryMode(step.Mode, dialect, query) {
+		result, err := tx.Statement.ConnPool.ExecContext(ctx, query, queryParams...)
+		if err != nil {
+			r.logger.Error().Err(err).Str("query", query).Msg("failed to execute step write query")
+			return nil, err
+		}
+
+		if output.AffectedRows, err = result.RowsAffected(); err != nil {
+			r.logger.Error().Err(err).Str("query", query).Msg("failed to get step write query affected rows")
+			return nil, err
+		}
+		if lastInsertId, err := result.LastInsertId(); err == nil {
+			results[step.Name+".last_insert_id"] = lastInsertId
+		}
+
+		return output, nil
+	}
+
+	rows, err := tx.Statement.ConnPool.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to execute step select query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Rows, err = scanRows(rows, newTypeMapping(ctx, dialect)); err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to read step select query rows")
+		return nil, err
+	}
+	output.AffectedRows = int64(len(output.Rows))
+
+	for _, column := range columns {
+		var value any
+		if len(output.Rows) > 0 {
+			value = output.Rows[0][column]
+		}
+		results[step.Name+"."+column] = value
+	}
+
+	return output, nil
+}
+
+// CallProcedure calls a stored procedure on a connection of its own, outside of a transaction, so that the
+// procedure can commit its own work. Every result set of the procedure is returned, the first one as the rows,
+// along with the values of its out and inout params.
+func (r *SQLQueryRepo) CallProcedure(ctx context.Context, db *gorm.DB, procedure dto.ProcedureCall, params map[string]any) (*dto.SQLQueryOutput, error) {
+	dialect := db.Dialector.Name()
+
+	procedureParams := make([]sqlqueryhelper.ProcedureParam, len(procedure.Params))
+	for idx, param := range procedure.Params {
+		procedureParams[idx] = sqlqueryhelper.ProcedureParam{Name: param.Name, Direction: param.Direction}
+	}
+
+	call, err := sqlqueryhelper.CallQuery(dialect, procedure.Name, procedureParams)
+	if err != nil {
+		r.logger.Error().Err(err).Str("procedure", procedure.Name).Msg("failed to build procedure call")
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to get datasource connection pool")
+		return nil, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to get datasource connection")
+		return nil, err
+	}
+	defer conn.Close()
+
+	// mysql session variables of the inout params must be set on the connection of the call
+	if call.Setup != "" {
+		setupQuery, setupParams, err := sqlqueryhelper.TransformQuery(dialect, call.Setup, params)
+		if err != nil {
+			r.logger.Error().Err(err).Str("procedure", procedure.Name).Msg("failed to bind procedure inout params")
+			return nil, err
+		}
+		if _, err := conn.ExecContext(ctx, setupQuery, setupParams...); err != nil {
+			r.logger.Error().Err(err).Str("query", setupQuery).Msg("failed to set procedure inout params")
+			return nil, err
+		}
+	}
+
+	query, args, err := sqlqueryhelper.TransformQuery(dialect, call.Query, params)
+	if err != nil {
+		r.logger.Error().Err(err).Str("procedure", procedure.Name).Msg("failed to bind procedure params")
+		return nil, err
+	}
+	var outDests map[string]sql.Scanner
+	if dialect == sqllexer.SQLServerDialect {
+		if args, outDests, err = namedArgs(procedure.Params, params); err != nil {
+			r.logger.Error().Err(err).Str("procedure", procedure.Name).Msg("failed to bind procedure params")
+			return nil, err
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to call procedure")
+		return nil, err
+	}
+
+	resultSets, err := scanResultSets(rows, newTypeMapping(ctx, dialect))
+	// sqlserver sets the out params once the rows are closed
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Str("query", query).Msg("failed to read procedure result sets")
+		return nil, err
+	}
+
+	output := &dto.SQLQueryOutput{}
+	switch {
+	case call.OutRow:
+		if len(resultSets) > 0 && len(resultSets[0]) > 0 {
+			output.OutParams = resultSets[0][0]
+		}
+		resultSets = nil
+	case call.Fetch != "":
+		fetched, err := conn.QueryContext(ctx, call.Fetch)
+		if err != nil {
+			r.logger.Error().Err(err).Str("query", call.Fetch).Msg("failed to fetch procedure out params")
+			return nil, err
+		}
+		defer fetched.Close()
+
+		fetchedRows, err := scanRows(fetched, newTypeMapping(ctx, dialect))
+		if err != nil {
+			r.logger.Error().Err(err).Str("query", call.Fetch).Msg("failed to read procedure out params")
+			return nil, err
+		}
+		if len(fetchedRows) > 0 {
+			output.OutParams = fetchedRows[0]
+		}
+	case len(outDests) > 0:
+		output.OutParams = make(map[string]any, len(outDests))
+		for name, dest := range outDests {
+			output.OutParams[name] = scannedValue(dest)
+		}
+	}
+
+	if len(resultSets) > 0 {
+		output.Rows = resultSets[0]
+		output.AffectedRows = int64(len(output.Rows))
+		output.ResultSets = resultSets
+	}
+
+	return output, nil
+}
+
+// namedArgs binds the params of a sqlserver procedure by name. Out and inout params are bound to a destination
+// of their declared type, holding the value of an inout param, which receives their value after the call.
+func namedArgs(procedureParams []dto.ProcedureParam, params map[string]any) ([]any, map[string]sql.Scanner, error) {
+	args := make([]any, len(procedureParams))
+	outDests := make(map[string]sql.Scanner)
+
+	for idx, param := range procedureParams {
+		if param.Direction != sqlqueryhelper.OutDirection && param.Direction != sqlqueryhelper.InOutDirection {
+			args[idx] = sql.Named(param.Name, params[param.Name])
+			continue
+		}
+
+		dest := outDest(param.Type)
+		inout := param.Direction == sqlqueryhelper.InOutDirection
+		if value := params[param.Name]; inout && value != nil {
+			if err := dest.Scan(value); err != nil {
+				return nil, nil, fmt.Errorf("param %s: %w", param.Name, err)
+			}
+		}
+
+		outDests[param.Name] = dest
+		args[idx] = sql.Named(param.Name, sql.Out{Dest: dest, In: inout})
+	}
+
+	return args, outDests, nil
+}
+
+// outDest allocates the destination of an out param, sqlserver needs its type even when its value is NULL
+func outDest(paramType string) sql.Scanner {
+	switch paramType {
+	case "int":
+		return &sql.NullInt64{}
+	case "float":
+		return &sql.NullFloat64{}
+	case "bool":
+		return &sql.NullBool{}
+	case "date", "datetime":
+		return &sql.NullTime{}
+	default:
+		return &sql.NullString{}
+	}
 }
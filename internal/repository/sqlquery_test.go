@@ -0,0 +1,236 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/database/external"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T, names ...string) *external.Registry {
+	t.Helper()
+
+	databases := make([]config.Database, 0, len(names))
+	for _, name := range names {
+		databases = append(databases, config.Database{
+			Name:    name,
+			Type:    "sqlite",
+			Dbname:  filepath.Join(t.TempDir(), name),
+			Timeout: time.Second,
+		})
+	}
+
+	registry := external.NewRegistry(databases)
+	t.Cleanup(func() {
+		if err := registry.Close(); err != nil {
+			t.Errorf("failed to close registry: %v", err)
+		}
+	})
+
+	return registry
+}
+
+func TestSQLQueryRepo_ConcurrentExecuteOnDifferentTargets(t *testing.T) {
+	t.Parallel()
+
+	const iterations = 50
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	datasources := []string{"school_a", "school_b"}
+	registry := newTestRegistry(t, datasources...)
+
+	for _, name := range datasources {
+		cnx, _, err := registry.Get(name)
+		if err != nil {
+			t.Fatalf("failed to get datasource %s: %v", name, err)
+		}
+
+		if err := repo.ExecuteInit(ctx, cnx, []string{"create table student (id integer primary key, name text)"}); err != nil {
+			t.Fatalf("failed to init datasource %s: %v", name, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, iterations*len(datasources))
+
+	for i := range iterations {
+		for _, name := range datasources {
+			wg.Add(1)
+			go func(id int, datasource string) {
+				defer wg.Done()
+
+				cnx, _, err := registry.Get(datasource)
+				if err != nil {
+					errCh <- err
+					return
+				}
+
+				params := map[string]any{"id": id, "name": datasource}
+				if _, err := repo.Execute(ctx, cnx, "insert into student (id, name) values ({{id}}, {{name}})", "", params); err != nil {
+					errCh <- fmt.Errorf("insert into %s: %w", datasource, err)
+					return
+				}
+
+				if _, err := repo.Execute(ctx, cnx, "select * from student where id = {{id}}", "", params); err != nil {
+					errCh <- fmt.Errorf("select from %s: %w", datasource, err)
+				}
+			}(i, name)
+		}
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Error(err)
+	}
+
+	for _, name := range datasources {
+		cnx, _, err := registry.Get(name)
+		if err != nil {
+			t.Fatalf("failed to get datasource %s: %v", name, err)
+		}
+
+		output, err := repo.Execute(ctx, cnx, "select * from student where name = {{name}}", "", map[string]any{"name": name})
+		if err != nil {
+			t.Fatalf("failed to count rows of %s: %v", name, err)
+		}
+
+		if output.AffectedRows != iterations {
+			t.Errorf("datasource %s has %d rows, want %d", name, output.AffectedRows, iterations)
+		}
+	}
+}
+
+func TestSQLQueryRepo_ExecuteExpandsListParams(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "school").Get("school")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table student (id integer primary key, name text)",
+		"insert into student values (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd')",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	testCases := []struct {
+		query    string
+		ids      sqlqueryhelper.List
+		expected int
+	}{
+		{"select * from student where id in ({{ids}})", sqlqueryhelper.List{int64(1), int64(3), int64(9)}, 2},
+		{"select * from student where id in ({{ids}})", sqlqueryhelper.List{}, 0},
+		{"select * from student where id not in ({{ids}})", sqlqueryhelper.List{}, 4},
+	}
+
+	for _, testCase := range testCases {
+		result, err := repo.Execute(ctx, cnx, testCase.query, "query", map[string]any{"ids": testCase.ids})
+		if err != nil {
+			t.Fatalf("failed to execute %q with %v: %v", testCase.query, testCase.ids, err)
+		}
+
+		if len(result.Rows) != testCase.expected {
+			t.Errorf("%q with %v returned %d rows, want %d", testCase.query, testCase.ids, len(result.Rows), testCase.expected)
+		}
+	}
+}
+
+func TestSQLQueryRepo_ExecuteRenderedTemplateBindsValues(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "school").Get("school")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table student (id integer primary key, name text)",
+		"insert into student values (1, 'a'), (2, 'b')",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	query := "select * from student where 1 = 1{{#if name}} and name = {{name}}{{/if}}"
+	for _, name := range []string{"' or '1' = '1", "{{/if}} or 1 = 1 --", "a'; drop table student; --"} {
+		params := map[string]any{"name": name}
+		rendered, err := sqlqueryhelper.RenderTemplate(query, params)
+		if err != nil {
+			t.Fatalf("failed to render template: %v", err)
+		}
+
+		result, err := repo.Execute(ctx, cnx, rendered, "query", params)
+		if err != nil {
+			t.Fatalf("failed to execute with %q: %v", name, err)
+		}
+		if len(result.Rows) != 0 {
+			t.Errorf("%q returned %d rows, want 0", name, len(result.Rows))
+		}
+	}
+
+	result, err := repo.Execute(ctx, cnx, "select * from student", "query", nil)
+	if err != nil || len(result.Rows) != 2 {
+		t.Errorf("student table changed: %v, %v", result, err)
+	}
+}
+
+func TestSQLQueryRepo_ExecuteNormalizesColumnTypes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "shop").Get("shop")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table product (id integer primary key, price decimal(10,2), created_at datetime, photo blob)",
+		"insert into product values (1, 12.5, '2024-01-02 03:04:05', x'0001ff')",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	query := "select id, price, created_at, photo from product"
+	testCases := []struct {
+		columnTypes map[string]string
+		expected    map[string]any
+	}{
+		{
+			nil,
+			map[string]any{"id": int64(1), "price": "12.5", "created_at": "2024-01-02T03:04:05Z", "photo": "AAH/"},
+		},
+		{
+			map[string]string{"price": "number", "created_at": "date", "photo": "raw"},
+			map[string]any{"id": int64(1), "price": 12.5, "created_at": "2024-01-02", "photo": "\x00\x01\xff"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		result, err := repo.Execute(repository.WithColumnTypes(ctx, testCase.columnTypes), cnx, query, sqlqueryhelper.QueryMode, nil)
+		if err != nil {
+			t.Fatalf("failed to execute query with %v: %v", testCase.columnTypes, err)
+		}
+
+		if !reflect.DeepEqual(result.Rows[0], testCase.expected) {
+			t.Errorf("got row %#v with %v, want %#v", result.Rows[0], testCase.columnTypes, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSQLQueryRepo_ExecuteSteps(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "shop").Get("shop")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table invoice (id integer primary key autoincrement, customer text not null)",
+		"create table invoice_line (invoice_id integer not null, product text not null)",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	steps := []dto.SQLStep{
+		{Name: "header", Query: "insert into invoice (customer) values ({{customer}})"},
+		{Name: "lines", Query: "insert into invoice_line (invoice_id, product) values ({{header.last_insert_id}}, {{product}}), ({{header.last_insert_id}}, 'bag')"},
+		{Name: "created", Query: "select id, customer from invoice where id = {{header.last_insert_id}}"},
+		{Name: "count", Query: "select count(*) as total from invoice_line where invoice_id = {{created.id}}"},
+	}
+
+	outputs, err := repo.ExecuteSteps(ctx, cnx, steps, map[string]any{"customer": "bob", "product": "pen"})
+	if err != nil {
+		t.Fatalf("failed to execute steps: %v", err)
+	}
+
+	if len(outputs) != len(steps) {
+		t.Fatalf("got %d step outputs, want %d", len(outputs), len(steps))
+	}
+	if outputs[0].AffectedRows != 1 || outputs[1].AffectedRows != 2 {
+		t.Errorf("got affected rows %d and %d, want 1 and 2", outputs[0].AffectedRows, outputs[1].AffectedRows)
+	}
+	if len(outputs[2].Rows) != 1 || outputs[2].Rows[0]["customer"] != "bob" {
+		t.Errorf("got created rows %v", outputs[2].Rows)
+	}
+	if total := outputs[3].Rows[0]["total"]; total != int64(2) {
+		t.Errorf("got %v lines, want 2", total)
+	}
+}
+
+func TestSQLQueryRepo_ExecuteStepsRollsBackOnFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "shop").Get("shop")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table invoice (id integer primary key autoincrement, customer text not null)",
+		"create table invoice_line (invoice_id integer not null, product text not null)",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	steps := []dto.SQLStep{
+		{Name: "header", Query: "insert into invoice (customer) values ({{customer}})"},
+		{Name: "lines", Query: "insert into invoice_line (invoice_id, product) values ({{header.last_insert_id}}, {{product}})"},
+	}
+
+	_, err = repo.ExecuteSteps(ctx, cnx, steps, map[string]any{"customer": "bob", "product": nil})
+	var stepErr *dto.StepError
+	if !errors.As(err, &stepErr) {
+		t.Fatalf("got error %v, want the lines step to fail with a StepError", err)
+	}
+	if stepErr.Index != 1 || stepErr.Name != "lines" {
+		t.Errorf("got failed step %d %s, want 1 lines", stepErr.Index, stepErr.Name)
+	}
+
+	result, err := repo.Execute(ctx, cnx, "select * from invoice", "query", nil)
+	if err != nil {
+		t.Fatalf("failed to read invoices: %v", err)
+	}
+	if len(result.Rows) != 0 {
+		t.Errorf("got %d invoices after the rollback, want 0", len(result.Rows))
+	}
+}
+
+func TestSQLQueryRepo_ExecuteStepsBindsStepParams(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "shop").Get("shop")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{"create table customer (id integer primary key, name text not null)"}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	steps := []dto.SQLStep{
+		{Name: "first", Query: "insert into customer (id, name) values ({{id}}, {{name}})", Params: map[string]any{"id": 1, "name": "bob"}},
+		{Name: "second", Query: "insert into customer (id, name) values ({{id}}, {{name}})", Params: map[string]any{"id": 2, "name": "alice"}},
+		{Name: "names", Query: "select name from customer where id in ({{first.last_insert_id}}, {{second.last_insert_id}}) order by id"},
+	}
+
+	outputs, err := repo.ExecuteSteps(ctx, cnx, steps, nil)
+	if err != nil {
+		t.Fatalf("failed to execute steps: %v", err)
+	}
+
+	rows := outputs[2].Rows
+	if len(rows) != 2 || rows[0]["name"] != "bob" || rows[1]["name"] != "alice" {
+		t.Errorf("got rows %v, want bob then alice", rows)
+	}
+}
@@ -0,0 +1,86 @@
+package repository_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/logging"
+
+	"context"
+	"errors"
+	"testing"
+)
+
+type collectWriter struct {
+	columns []string
+	rows    [][]any
+	onRow   func(count int)
+}
+
+func (w *collectWriter) WriteColumns(columns []string) error {
+	w.columns = columns
+	return nil
+}
+
+func (w *collectWriter) WriteRow(values []any) error {
+	w.rows = append(w.rows, values)
+	if w.onRow != nil {
+		w.onRow(len(w.rows))
+	}
+
+	return nil
+}
+
+func TestSQLQueryRepo_Stream(t *testing.T) {
+	t.Parallel()
+
+	const total = 2500
+
+	ctx := context.Background()
+	repo := repository.NewSQLQueryRepo(logging.InitLogger())
+	cnx, _, err := newTestRegistry(t, "school").Get("school")
+	if err != nil {
+		t.Fatalf("failed to get datasource: %v", err)
+	}
+
+	if err := repo.ExecuteInit(ctx, cnx, []string{
+		"create table student (id integer primary key, name text)",
+		"with recursive seq(n) as (select 1 union all select n + 1 from seq where n < 2500) insert into student select n, 'student ' || n from seq",
+	}); err != nil {
+		t.Fatalf("failed to init datasource: %v", err)
+	}
+
+	writer := &collectWriter{}
+	count, err := repo.Stream(ctx, cnx, "select id, name from student where id > {{min}} order by id", map[string]any{"min": 0}, writer)
+	if err != nil {
+		t.Fatalf("failed to stream rows: %v", err)
+	}
+
+	if count != total || len(writer.rows) != total {
+		t.Fatalf("got %d rows (%d written), want %d", count, len(writer.rows), total)
+	}
+	if len(writer.columns) != 2 || writer.columns[0] != "id" || writer.columns[1] != "name" {
+		t.Errorf("got columns %v, want [id name]", writer.columns)
+	}
+	if writer.rows[total-1][0] != int64(total) || writer.rows[0][1] != "student 1" {
+		t.Errorf("unexpected rows: first %v, last %v", writer.rows[0], writer.rows[total-1])
+	}
+
+	empty := &collectWriter{}
+	if count, err := repo.Stream(ctx, cnx, "select id from student where id < 0", nil, empty); err != nil || count != 0 || len(empty.columns) != 1 {
+		t.Errorf("got %d rows, columns %v, error %v, want no row and the id column", count, empty.columns, err)
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cancelled := &collectWriter{onRow: func(count int) {
+		if count == 10 {
+			cancel()
+		}
+	}}
+	if _, err := repo.Stream(cancelCtx, cnx, "select id from student", nil, cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if len(cancelled.rows) >= total {
+		t.Errorf("got %d rows, want the stream to stop after the cancellation", len(cancelled.rows))
+	}
+}
@@ -6,31 +6,43 @@ import (
 	"github.com/willbrid/api-gateway-sql/internal/domain"
 	"github.com/willbrid/api-gateway-sql/internal/dto/paginator"
 	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/staging"
 
 	"context"
+	"time"
 )
 
 type BatchStatUsecase struct {
-	repo   *repository.BatchStatRepo
-	logger zerolog.Logger
+	repo    *repository.BatchStatRepo
+	staging *staging.Store
+	logger  zerolog.Logger
 }
 
-func NewBatchStatUsecase(batchStatRepo *repository.BatchStatRepo, logger zerolog.Logger) *BatchStatUsecase {
+func NewBatchStatUsecase(batchStatRepo *repository.BatchStatRepo, staging *staging.Store, logger zerolog.Logger) *BatchStatUsecase {
 	return &BatchStatUsecase{
-		repo:   batchStatRepo,
-		logger: logger.With().Str("layer", "usecase").Str("component", "batchstat").Logger(),
+		repo:    batchStatRepo,
+		staging: staging,
+		logger:  logger.With().Str("layer", "usecase").Str("component", "batchstat").Logger(),
 	}
 }
 
-func (b *BatchStatUsecase) IsAllBatchStatClosed(ctx context.Context) (bool, error) {
-	totalUncompletedBatchStat, err := b.repo.CountUncompletedBatchStat(ctx)
+// FailStaleBatches marks failed the running batches without heartbeat for staleAfter, such as the batches of a
+// stopped process, and removes their staged files
+func (b *BatchStatUsecase) FailStaleBatches(ctx context.Context, staleAfter time.Duration) (int64, error) {
+	failed, err := b.repo.FailStale(ctx, time.Now().Add(-staleAfter))
 	if err != nil {
-		b.logger.Error().Err(err).Msg("failed to get uncompleted batchstat")
-		return false, err
+		b.logger.Error().Err(err).Msg("failed to mark stale batchstat failed")
+		return 0, err
 	}
 
-	b.logger.Info().Msg("uncompleted batchstat count retrieved")
-	return totalUncompletedBatchStat <= 0, nil
+	for _, batchId := range failed {
+		if err := b.staging.Remove(batchId); err != nil {
+			b.logger.Error().Err(err).Str("batch_id", batchId).Msg("failed to remove the staged csv file")
+		}
+	}
+
+	b.logger.Info().Int("failed", len(failed)).Msg("stale batchstat marked failed")
+	return int64(len(failed)), nil
 }
 
 func (b *BatchStatUsecase) ListBatchStats(ctx context.Context, pageRequest *paginator.PageRequest) (*paginator.PageResponse, error) {
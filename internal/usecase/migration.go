@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/willbrid/api-gateway-sql/config"
+	"github.com/willbrid/api-gateway-sql/internal/domain"
+	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/migration"
+	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/database/external"
+
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type MigrationUsecase struct {
+	repo        *repository.MigrationRepo
+	datasources *external.Registry
+	config      *config.Store
+	locks       sync.Map
+	logger      zerolog.Logger
+}
+
+func NewMigrationUsecase(repo *repository.MigrationRepo, datasources *external.Registry, config *config.Store, logger zerolog.Logger) *MigrationUsecase {
+	return &MigrationUsecase{
+		repo:        repo,
+		datasources: datasources,
+		config:      config,
+		logger:      logger.With().Str("layer", "usecase").Str("component", "migration").Logger(),
+	}
+}
+
+// Status lists the known and applied migrations of a datasource
+func (mu *MigrationUsecase) Status(ctx context.Context, input *dto.MigrationInput) ([]dto.MigrationStatus, error) {
+	cnx, release, migrations, err := mu.prepare(input, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	unlock := mu.lock(input.Datasource)
+	defer unlock()
+
+	return mu.status(ctx, cnx, migrations)
+}
+
+// Up applies the pending migrations of a datasource up to input.Version, or all of them when it is 0
+func (mu *MigrationUsecase) Up(ctx context.Context, input *dto.MigrationInput) ([]dto.MigrationStatus, error) {
+	cnx, release, migrations, err := mu.prepare(input, true)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	unlock := mu.lock(input.Datasource)
+	defer unlock()
+
+	applied, err := mu.repo.FindApplied(ctx, cnx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := migration.Pending(migrations, checksums(applied), input.Version)
+	if err != nil {
+		mu.logger.Error().Err(err).Str("datasource", input.Datasource).Msg("unable to plan migrations")
+		return nil, err
+	}
+
+	for _, m := range pending {
+		if err := mu.repo.Apply(ctx, cnx, m); err != nil {
+			mu.logger.Error().Err(err).Str("datasource", input.Datasource).Int64("version", m.Version).Msg("unable to apply migration")
+			return nil, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		mu.logger.Info().Str("datasource", input.Datasource).Int64("version", m.Version).Msg("migration applied")
+	}
+
+	return mu.status(ctx, cnx, migrations)
+}
+
+// Down reverts the input.Steps last applied migrations of a datasource
+func (mu *MigrationUsecase) Down(ctx context.Context, input *dto.MigrationInput) ([]dto.MigrationStatus, error) {
+	cnx, release, migrations, err := mu.prepare(input, true)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	unlock := mu.lock(input.Datasource)
+	defer unlock()
+
+	applied, err := mu.repo.FindApplied(ctx, cnx)
+	if err != nil {
+		return nil, err
+	}
+
+	rollback, err := migration.Rollback(migrations, checksums(applied), input.Steps)
+	if err != nil {
+		mu.logger.Error().Err(err).Str("datasource", input.Datasource).Msg("unable to plan rollback")
+		return nil, err
+	}
+
+	for _, m := range rollback {
+		if err := mu.repo.Revert(ctx, cnx, m); err != nil {
+			mu.logger.Error().Err(err).Str("datasource", input.Datasource).Int64("version", m.Version).Msg("unable to revert migration")
+			return nil, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		mu.logger.Info().Str("datasource", input.Datasource).Int64("version", m.Version).Msg("migration reverted")
+	}
+
+	return mu.status(ctx, cnx, migrations)
+}
+
+// prepare returns the datasource connection with the function releasing it, and its migrations, from the uploaded
+// files or the migrations_dir
+func (mu *MigrationUsecase) prepare(input *dto.MigrationInput, sourceRequired bool) (*gorm.DB, func(), []migration.Migration, error) {
+	database, exist := mu.config.Get().GetDatabaseByDataSourceName(input.Datasource)
+	if !exist {
+		mu.logger.Error().Msg(errUnknownDatasource.Error())
+		return nil, nil, nil, errUnknownDatasource
+	}
+
+	var (
+		migrations []migration.Migration
+		err        error
+	)
+	switch {
+	case len(input.Files) > 0:
+		migrations, err = migration.Parse(input.Files)
+	case database.MigrationsDir != "":
+		migrations, err = migration.LoadDir(database.MigrationsDir)
+	case sourceRequired:
+		err = fmt.Errorf("%w: no migration uploaded and no migrations_dir configured for %s", migration.ErrInvalidMigration, database.Name)
+	}
+	if err != nil {
+		mu.logger.Error().Err(err).Str("datasource", database.Name).Msg("unable to load migrations")
+		return nil, nil, nil, err
+	}
+
+	cnx, release, err := mu.datasources.Get(database.Name)
+	if err != nil {
+		mu.logger.Error().Err(err).Msg("unable to get database connection")
+		return nil, nil, nil, err
+	}
+
+	return cnx, release, migrations, nil
+}
+
+func (mu *MigrationUsecase) status(ctx context.Context, cnx *gorm.DB, migrations []migration.Migration) ([]dto.MigrationStatus, error) {
+	applied, err := mu.repo.FindApplied(ctx, cnx)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]domain.SchemaMigration, len(applied))
+	for _, record := range applied {
+		byVersion[record.Version] = record
+	}
+
+	statuses := make([]dto.MigrationStatus, 0, len(migrations)+len(applied))
+	for _, m := range migrations {
+		status := dto.MigrationStatus{Version: m.Version, Name: m.Name, State: dto.MigrationPending, Checksum: m.Checksum}
+		if record, isApplied := byVersion[m.Version]; isApplied {
+			status.State = dto.MigrationApplied
+			status.AppliedAt = record.AppliedAt
+			if record.Checksum != m.Checksum {
+				status.State = dto.MigrationModified
+			}
+			delete(byVersion, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// applied migrations which are not in the uploaded files or the migrations_dir
+	for _, record := range byVersion {
+		statuses = append(statuses, dto.MigrationStatus{
+			Version:   record.Version,
+			Name:      record.Name,
+			State:     dto.MigrationUnknown,
+			Checksum:  record.Checksum,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+
+	slices.SortFunc(statuses, func(a, b dto.MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return statuses, nil
+}
+
+// lock serializes the migrations of a datasource, it returns the unlock function
+func (mu *MigrationUsecase) lock(datasource string) func() {
+	value, _ := mu.locks.LoadOrStore(datasource, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+
+	return mutex.Unlock
+}
+
+func checksums(applied []domain.SchemaMigration) map[int64]string {
+	result := make(map[int64]string, len(applied))
+	for _, record := range applied {
+		result[record.Version] = record.Checksum
+	}
+
+	return result
+}
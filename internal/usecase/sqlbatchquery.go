@@ -8,60 +8,138 @@ import (
 	"github.com/willbrid/api-gateway-sql/internal/dto"
 	"github.com/willbrid/api-gateway-sql/internal/pkg/confighelper"
 	"github.com/willbrid/api-gateway-sql/internal/pkg/csvmapper"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlscript"
 	"github.com/willbrid/api-gateway-sql/internal/repository"
 	"github.com/willbrid/api-gateway-sql/pkg/csvstream"
 	"github.com/willbrid/api-gateway-sql/pkg/database/external"
+	"github.com/willbrid/api-gateway-sql/pkg/jobmanager"
+	"github.com/willbrid/api-gateway-sql/pkg/staging"
 
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 var (
 	errBatchModeNotActivated = errors.New("attribut multi for batch mode is not activate for this target")
+
+	ErrInvalidBatch      error = errors.New("invalid batch")
+	ErrTooManyBatches    error = errors.New("too many running batches")
+	ErrBatchNotFound     error = errors.New("batch not found")
+	ErrBatchNotRetryable error = errors.New("batch can't be retried")
+	ErrBatchNotRunning   error = errors.New("batch is not running")
+)
+
+// Lock scopes of the batches
+const (
+	targetLockScope     string = "target"
+	datasourceLockScope string = "datasource"
 )
 
+// heartbeatInterval is the period of the heartbeats of a running batch, below the minimum stale_after
+const heartbeatInterval time.Duration = 30 * time.Second
+
 type SQLBatchQueryUsecase struct {
 	sqlQueryRepo  *repository.SQLQueryRepo
 	batchStatRepo *repository.BatchStatRepo
 	blockRepo     *repository.BlockRepo
-	config        *config.Config
+	datasources   *external.Registry
+	staging       *staging.Store
+	config        *config.Store
 	logger        zerolog.Logger
+	// slots serializes the checks of the running batches of a lock scope with the start of a batch
+	slots sync.Mutex
+	// jobs holds the batches run by the process, to cancel them
+	jobs *jobmanager.Manager
 }
 
-func NewSQLBatchQueryUsecase(sqlQueryRepo *repository.SQLQueryRepo, batchStatRepo *repository.BatchStatRepo, blockRepo *repository.BlockRepo, config *config.Config, logger zerolog.Logger) *SQLBatchQueryUsecase {
+func NewSQLBatchQueryUsecase(sqlQueryRepo *repository.SQLQueryRepo, batchStatRepo *repository.BatchStatRepo, blockRepo *repository.BlockRepo, datasources *external.Registry, staging *staging.Store, config *config.Store, logger zerolog.Logger) *SQLBatchQueryUsecase {
 	return &SQLBatchQueryUsecase{
 		sqlQueryRepo:  sqlQueryRepo,
 		batchStatRepo: batchStatRepo,
 		blockRepo:     blockRepo,
+		datasources:   datasources,
+		staging:       staging,
 		config:        config,
 		logger:        logger.With().Str("layer", "usecase").Str("component", "sqlbatchquery").Logger(),
+		jobs:          jobmanager.NewManager(),
 	}
 }
 
-func (squ *SQLBatchQueryUsecase) ExecuteBatch(ctx context.Context, sqlbatchquery *dto.SQLBatchQueryInput) error {
-	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config, sqlbatchquery.TargetName)
+// PrepareBatch registers a batch of a target as running and stages its csv file, when fewer than
+// max_concurrent_batches batches run in its lock scope
+func (squ *SQLBatchQueryUsecase) PrepareBatch(ctx context.Context, sqlbatchquery *dto.SQLBatchQueryInput) (*dto.BatchRun, error) {
+	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config.Get(), sqlbatchquery.TargetName)
 	if err != nil {
 		squ.logger.Error().Err(err).Msg("unable to get target and database from config")
-		return err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
 	}
 
 	if !target.Multi {
-		squ.logger.Error().Err(err).Msg(errBatchModeNotActivated.Error())
-		return errBatchModeNotActivated
+		squ.logger.Error().Msg(errBatchModeNotActivated.Error())
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBatch, errBatchModeNotActivated)
 	}
 
-	batchStat, err := squ.batchStatRepo.Create(ctx, target.Name)
+	var batchStat *domain.BatchStat
+	err = squ.reserve(ctx, target, func() error {
+		if batchStat, err = squ.batchStatRepo.Create(ctx, target.Name, target.DataSourceName); err != nil {
+			return err
+		}
+		squ.jobs.Register(batchStat.ID)
+		return nil
+	})
 	if err != nil {
 		squ.logger.Error().Err(err).Str("target", target.Name).Msg("failed to create a batch")
-		return err
+		return nil, err
+	}
+
+	// the file is staged to read it after the request, and to retry its failed lines later
+	if err := squ.staging.Save(batchStat.ID, sqlbatchquery.File); err != nil {
+		squ.logger.Error().Err(err).Str("batch_id", batchStat.ID).Msg("failed to stage the csv file")
+		squ.jobs.Remove(batchStat.ID)
+		return nil, squ.finalizeWithError(ctx, batchStat, err)
 	}
 
-	blockCh, errCh := csvstream.ReadCSVInBlock(sqlbatchquery.File, target.BufferSize)
+	return &dto.BatchRun{BSInput: batchStat, TGInput: target, DBInput: cfgdb}, nil
+}
+
+// ExecuteBatch runs the lines of a staged csv file by blocks, until the end of the file or the cancellation of
+// the batch. A cancelled batch stops reading its file, its running transactions commit or roll back, and it
+// records the last line handed over to its blocks.
+func (squ *SQLBatchQueryUsecase) ExecuteBatch(ctx context.Context, run *dto.BatchRun) error {
+	batchStat, target, cfgdb := run.BSInput, run.TGInput, run.DBInput
+
+	// the statistics of the batch are still recorded once it is cancelled
+	statCtx := context.WithoutCancel(ctx)
+	ctx, done := squ.jobs.Run(ctx, batchStat.ID)
+	defer done()
+	defer squ.keepAlive(statCtx, batchStat.ID)()
+
+	file, err := squ.staging.Open(batchStat.ID)
+	if err != nil {
+		return squ.finalizeWithError(statCtx, batchStat, err)
+	}
+	defer file.Close()
+
+	blockCh, errCh := csvstream.ReadCSVInBlock(ctx, file, target.BufferSize, csvFormat(target))
 
 	var wg sync.WaitGroup
+	lastLine := 0
 	for block := range blockCh {
+		// a block read along with the cancellation is dropped
+		if ctx.Err() != nil {
+			continue
+		}
+
+		lastLine = block.EndLine
 		wg.Add(1)
 		go func(b *csvstream.Block) {
 			defer wg.Done()
@@ -74,18 +152,402 @@ func (squ *SQLBatchQueryUsecase) ExecuteBatch(ctx context.Context, sqlbatchquery
 		}(block)
 	}
 
-	if err := <-errCh; err != nil {
-		wg.Wait()
-		return squ.finalizeWithError(ctx, batchStat, err)
+	readErr := <-errCh
+	wg.Wait()
+	batchStat.LastLine = lastLine
+
+	// the staged file is only kept to retry the failure ranges, the lines after the last one of a cancelled batch
+	// aren't run again
+	squ.releaseStagedFile(statCtx, batchStat.ID)
+	if ctx.Err() != nil {
+		return squ.batchStatRepo.MarkCancelled(statCtx, batchStat)
+	}
+	if readErr != nil {
+		return squ.finalizeWithError(statCtx, batchStat, readErr)
 	}
 
-	wg.Wait()
-	return squ.batchStatRepo.UpdateLastCompleted(ctx, batchStat)
+	return squ.batchStatRepo.UpdateLastCompleted(statCtx, batchStat)
 }
 
+// reserve runs start, which creates or reopens a batch of target, while fewer than max_concurrent_batches
+// batches run in the lock scope of the target. Stale batches are marked failed, and their staged files removed,
+// before the running batches are counted.
+func (squ *SQLBatchQueryUsecase) reserve(ctx context.Context, target *config.Target, start func() error) error {
+	batches := squ.config.Get().ApiGatewaySQL.Batches
+
+	squ.slots.Lock()
+	defer squ.slots.Unlock()
+
+	stale, err := squ.batchStatRepo.FailStale(ctx, time.Now().Add(-batches.StaleAfter))
+	if err != nil {
+		return err
+	}
+	for _, batchId := range stale {
+		squ.removeStagedFile(batchId)
+	}
+
+	countRunning, scope, name := squ.batchStatRepo.CountRunningByTarget, targetLockScope, target.Name
+	if batches.LockScope == datasourceLockScope {
+		countRunning, scope, name = squ.batchStatRepo.CountRunningByDataSource, datasourceLockScope, target.DataSourceName
+	}
+
+	running, err := countRunning(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if running >= int64(batches.MaxConcurrentBatches) {
+		return fmt.Errorf("%w: %d running batches for %s %s", ErrTooManyBatches, running, scope, name)
+	}
+
+	return start()
+}
+
+// keepAlive updates the heartbeat of a running batch until the returned function is called
+func (squ *SQLBatchQueryUsecase) keepAlive(ctx context.Context, batchId string) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = squ.batchStatRepo.Heartbeat(ctx, batchId)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// PrepareRetry checks that the failed lines of a batch can be run again and marks the batch as running. The
+// batch must be completed and retried fewer than max_retries times, its target must still be a batch target and
+// its csv file must be staged.
+func (squ *SQLBatchQueryUsecase) PrepareRetry(ctx context.Context, input *dto.SQLBatchRetryInput) (*dto.BatchRetry, error) {
+	batchStat, err := squ.batchStatRepo.FindWithFailures(ctx, input.BatchStatID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, input.BatchStatID)
+	}
+	if err != nil {
+		squ.logger.Error().Err(err).Str("batch_id", input.BatchStatID).Msg("unable to get batch to retry")
+		return nil, err
+	}
+
+	if !batchStat.Completed {
+		return nil, fmt.Errorf("%w: batch %s is running", ErrBatchNotRetryable, batchStat.ID)
+	}
+	if maxRetries := squ.config.Get().ApiGatewaySQL.Batches.MaxRetries; batchStat.Retries >= maxRetries {
+		return nil, fmt.Errorf("%w: batch %s was retried %d times", ErrBatchNotRetryable, batchStat.ID, batchStat.Retries)
+	}
+	if len(failedLines(batchStat)) == 0 {
+		return nil, fmt.Errorf("%w: batch %s has no failed lines", ErrBatchNotRetryable, batchStat.ID)
+	}
+
+	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config.Get(), batchStat.TargetName)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to get target and database from config")
+		return nil, err
+	}
+	if !target.Multi {
+		return nil, fmt.Errorf("%w: %v", ErrBatchNotRetryable, errBatchModeNotActivated)
+	}
+
+	file, err := squ.staging.Open(batchStat.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBatchNotRetryable, err)
+	}
+	_ = file.Close()
+
+	sqlQuery := input.SqlQuery
+	if sqlQuery == "" {
+		sqlQuery = target.SqlQuery
+	} else {
+		if err := squ.checkRetryQuery(target, cfgdb, sqlQuery); err != nil {
+			return nil, err
+		}
+		squ.logger.Info().Str("batch_id", batchStat.ID).Str("sql", sqlQuery).Msg("batch retried with a replacement sql")
+	}
+
+	err = squ.reserve(ctx, target, func() error {
+		if err := squ.batchStatRepo.Reopen(ctx, batchStat); err != nil {
+			return err
+		}
+		squ.jobs.Register(batchStat.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.BatchRetry{BSInput: batchStat, TGInput: target, DBInput: cfgdb, SqlQuery: sqlQuery}, nil
+}
+
+// checkRetryQuery refuses a replacement sql which wouldn't run like the sql of a batch target : it must be a
+// single statement, which doesn't return rows and only binds the batch fields.
+func (squ *SQLBatchQueryUsecase) checkRetryQuery(target *config.Target, cfgdb *config.Database, sqlQuery string) error {
+	cnx, release, err := squ.datasources.Get(cfgdb.Name)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("failed to get database connection")
+		return err
+	}
+	dialect := cnx.Dialector.Name()
+	release()
+
+	statements, err := sqlscript.Split(dialect, sqlQuery)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+	}
+	if len(statements) != 1 {
+		return fmt.Errorf("%w: the retry sql must be a single statement", ErrInvalidBatch)
+	}
+	if sqlqueryhelper.IsQueryMode(target.Mode, dialect, sqlQuery) {
+		return fmt.Errorf("%w: the retry sql must not return rows", ErrInvalidBatch)
+	}
+
+	batchFields := strings.Split(target.BatchFields, ";")
+	for _, name := range sqlqueryhelper.ParamNames(sqlQuery) {
+		if !slices.Contains(batchFields, name) {
+			return fmt.Errorf("%w: the retry sql uses %s which is not a batch field", ErrInvalidBatch, name)
+		}
+	}
+
+	return nil
+}
+
+// ExecuteRetry runs again the lines of each failure range of a batch, read from its staged file. A range whose
+// lines succeed is removed from its block, which counts a successful batch instead of a failed one, the other
+// ranges are kept. The batch is completed at the end, or cancelled when it is cancelled before its last range.
+// The staged file is removed after the last retry of the batch.
+func (squ *SQLBatchQueryUsecase) ExecuteRetry(ctx context.Context, retry *dto.BatchRetry) error {
+	batchId := retry.BSInput.ID
+
+	statCtx := context.WithoutCancel(ctx)
+	ctx, done := squ.jobs.Run(ctx, batchId)
+	defer done()
+	defer squ.keepAlive(statCtx, batchId)()
+	defer func() {
+		batchStat, err := squ.batchStatRepo.FindById(statCtx, batchId)
+		switch {
+		case err != nil:
+		case ctx.Err() != nil:
+			err = squ.batchStatRepo.MarkCancelled(statCtx, batchStat)
+		default:
+			err = squ.batchStatRepo.UpdateLastCompleted(statCtx, batchStat)
+		}
+		if err != nil {
+			squ.logger.Error().Err(err).Str("batch_id", batchId).Msg("failed to complete a retried batch")
+		}
+
+		// the staged file isn't read again once the retries of the batch are exhausted
+		if retry.BSInput.Retries >= squ.config.Get().ApiGatewaySQL.Batches.MaxRetries {
+			squ.removeStagedFile(batchId)
+		} else {
+			squ.releaseStagedFile(statCtx, batchId)
+		}
+	}()
+
+	cnx, release, err := squ.datasources.Get(retry.DBInput.Name)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("failed to get database connection")
+		return err
+	}
+	defer release()
+
+	failures := failedLines(retry.BSInput)
+	lines, err := squ.readStagedLines(ctx, batchId, retry.TGInput, failures)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("batch_id", batchId).Msg("failed to read the staged csv file")
+		return err
+	}
+
+	batchFields := strings.Split(retry.TGInput.BatchFields, ";")
+	for _, failure := range failures {
+		if ctx.Err() != nil {
+			break
+		}
+
+		rangeLines := make([][]string, 0, failure.last-failure.first+1)
+		for line := failure.first; line <= failure.last; line++ {
+			if values, exists := lines[line]; exists {
+				rangeLines = append(rangeLines, values)
+			}
+		}
+
+		records, err := csvmapper.MapBatchLines(rangeLines, batchFields)
+		if err != nil {
+			squ.logger.Error().Err(err).Int("start_line", failure.first).Int("end_line", failure.last).Msg("failed to map retried lines")
+			continue
+		}
+
+		if err := squ.sqlQueryRepo.ExecuteBatch(ctx, cnx, retry.SqlQuery, records); err != nil {
+			squ.logger.Error().Err(err).Int("start_line", failure.first).Int("end_line", failure.last).Msg("failed to execute retried lines")
+			continue
+		}
+
+		if err := squ.blockRepo.ResolveFailure(statCtx, failure.block, failure.failureRange); err != nil {
+			squ.logger.Error().Err(err).Msg("failed to update block after retry")
+		}
+	}
+
+	return nil
+}
+
+// CancelBatch cancels a batch run by the process, which is marked cancelled once its running transactions end.
+// A batch left running by a stopped process is marked cancelled at once.
+func (squ *SQLBatchQueryUsecase) CancelBatch(ctx context.Context, batchStatId string) error {
+	// a batch is registered while the slots are held, right after it is marked running
+	squ.slots.Lock()
+	defer squ.slots.Unlock()
+
+	if squ.jobs.Cancel(batchStatId) {
+		return nil
+	}
+
+	batchStat, err := squ.batchStatRepo.FindById(ctx, batchStatId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s", ErrBatchNotFound, batchStatId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if batchStat.Completed {
+		return fmt.Errorf("%w: batch %s is completed", ErrBatchNotRunning, batchStat.ID)
+	}
+
+	return squ.batchStatRepo.MarkCancelled(ctx, batchStat)
+}
+
+// failedRange is a failure range of a block, with the numbers of its first and last lines in the file
+type failedRange struct {
+	block        *domain.Block
+	failureRange *domain.FailureRange
+	first        int
+	last         int
+}
+
+// failedLines returns the failure ranges of a batch, ordered by line
+func failedLines(batchStat *domain.BatchStat) []failedRange {
+	var failures []failedRange
+	for blockIdx := range batchStat.Blocks {
+		block := &batchStat.Blocks[blockIdx]
+		for rangeIdx := range block.FailureRanges {
+			failureRange := &block.FailureRanges[rangeIdx]
+			failures = append(failures, failedRange{
+				block:        block,
+				failureRange: failureRange,
+				first:        failureRange.StartLine,
+				last:         failureRange.EndLine,
+			})
+		}
+	}
+
+	slices.SortFunc(failures, func(a, b failedRange) int { return a.first - b.first })
+	return failures
+}
+
+// readStagedLines reads the records of the failure ranges from the staged file of a batch, by record number
+func (squ *SQLBatchQueryUsecase) readStagedLines(ctx context.Context, batchId string, target *config.Target, failures []failedRange) (map[int][]string, error) {
+	file, err := squ.staging.Open(batchId)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	isFailed := func(line int) bool {
+		_, found := slices.BinarySearchFunc(failures, line, func(failure failedRange, line int) int {
+			switch {
+			case failure.last < line:
+				return -1
+			case failure.first > line:
+				return 1
+			default:
+				return 0
+			}
+		})
+		return found
+	}
+
+	lines := map[int][]string{}
+	blockCh, errCh := csvstream.ReadCSVInBlock(ctx, file, target.BufferSize, csvFormat(target))
+	for block := range blockCh {
+		for idx, values := range block.Lines {
+			if line := block.StartLine + idx; isFailed(line) {
+				lines[line] = values
+			}
+		}
+	}
+
+	return lines, <-errCh
+}
+
+// releaseStagedFile removes the staged file of a batch once it has no failed lines to retry
+func (squ *SQLBatchQueryUsecase) releaseStagedFile(ctx context.Context, batchId string) {
+	failures, err := squ.blockRepo.CountFailuresByBatchStatID(ctx, batchId)
+	if err != nil || failures > 0 {
+		return
+	}
+
+	squ.removeStagedFile(batchId)
+}
+
+// removeStagedFile removes the staged file of a batch whose failed lines won't be retried
+func (squ *SQLBatchQueryUsecase) removeStagedFile(batchId string) {
+	if err := squ.staging.Remove(batchId); err != nil {
+		squ.logger.Error().Err(err).Str("batch_id", batchId).Msg("failed to remove the staged csv file")
+	}
+}
+
+// csvFormat returns the format of the csv files of a target. With a header, the columns are the header names of
+// the batch fields, so that the lines hold the batch fields in their order.
+func csvFormat(target *config.Target) csvstream.Format {
+	format := csvstream.DefaultFormat
+	if target.CSV == nil {
+		return format
+	}
+
+	if target.CSV.Delimiter != "" {
+		format.Delimiter = []rune(target.CSV.Delimiter)[0]
+	}
+	if target.CSV.Quote != "" {
+		format.Quote = []rune(target.CSV.Quote)[0]
+		format.Escape = format.Quote
+	}
+	if target.CSV.Escape != "" {
+		format.Escape = []rune(target.CSV.Escape)[0]
+	}
+	if target.CSV.Encoding != "" {
+		format.Encoding = target.CSV.Encoding
+	}
+
+	if target.CSV.HasHeader {
+		headers := make(map[string]string, len(target.CSV.Columns))
+		for _, column := range target.CSV.Columns {
+			headers[column.Field] = column.Header
+		}
+
+		format.Header = true
+		for _, field := range strings.Split(target.BatchFields, ";") {
+			header, exists := headers[field]
+			if !exists {
+				header = field
+			}
+			format.Columns = append(format.Columns, header)
+		}
+	}
+
+	return format
+}
+
+// finalizeWithError marks failed a batch stopped before its end, whose staged file is removed
 func (squ *SQLBatchQueryUsecase) finalizeWithError(ctx context.Context, batchStat *domain.BatchStat, cause error) error {
-	if err := squ.batchStatRepo.UpdateLastCompleted(ctx, batchStat); err != nil {
-		squ.logger.Error().Err(err).Msg("failed to complete a batch")
+	squ.removeStagedFile(batchStat.ID)
+	if err := squ.batchStatRepo.MarkFailed(ctx, batchStat); err != nil {
+		squ.logger.Error().Err(err).Msg("failed to mark a batch failed")
 		return err
 	}
 
@@ -93,19 +555,18 @@ func (squ *SQLBatchQueryUsecase) finalizeWithError(ctx context.Context, batchSta
 }
 
 func (squ *SQLBatchQueryUsecase) processBlock(ctx context.Context, input *dto.BlockDataInput) {
-	block, err := squ.initBlock(ctx, input)
+	block, err := squ.initBlock(context.WithoutCancel(ctx), input)
 	if err != nil {
 		squ.logger.Error().Err(err).Msg("failed to initialize a block")
 		return
 	}
 
-	cnx, err := external.NewDatabase(*input.DBInput)
+	cnx, release, err := squ.datasources.Get(input.DBInput.Name)
 	if err != nil {
-		squ.logger.Error().Err(err).Msg("failed to open database connection")
+		squ.logger.Error().Err(err).Msg("failed to get database connection")
 		return
 	}
-	squ.sqlQueryRepo.SetDB(cnx)
-	defer squ.sqlQueryRepo.CloseDB()
+	defer release()
 
 	batchFields := strings.Split(input.TGInput.BatchFields, ";")
 	batches := csvmapper.ChunkLines(input.BLInput.Lines, input.TGInput.BatchSize)
@@ -115,7 +576,7 @@ func (squ *SQLBatchQueryUsecase) processBlock(ctx context.Context, input *dto.Bl
 		wg.Add(1)
 		go func(idx int, lines [][]string) {
 			defer wg.Done()
-			squ.processBatch(ctx, block, input, idx, lines, batchFields)
+			squ.processBatch(ctx, cnx, block, input, idx, lines, batchFields)
 		}(i, batch)
 	}
 	wg.Wait()
@@ -133,25 +594,115 @@ func (squ *SQLBatchQueryUsecase) initBlock(ctx context.Context, input *dto.Block
 	return block, nil
 }
 
-func (squ *SQLBatchQueryUsecase) processBatch(ctx context.Context, block *domain.Block, input *dto.BlockDataInput, idx int, lines [][]string, batchFields []string) {
+// processBatch runs the lines of a batch in a transaction. On failure, the lines of the batch are recorded as a
+// failure range, or with bisect_on_failure the batch is run again in halves and only the failing lines are
+// rejected. Failure ranges and rejected rows are numbered by record of the csv file. The lines of a batch rolled back or left
+// untried by the cancellation of its batch are recorded as a failure range, to retry them.
+func (squ *SQLBatchQueryUsecase) processBatch(ctx context.Context, cnx *gorm.DB, block *domain.Block, input *dto.BlockDataInput, idx int, lines [][]string, batchFields []string) {
+	firstLine := input.BLInput.StartLine + idx*input.TGInput.BatchSize
+	lastLine := firstLine + len(lines) - 1
+	statCtx := context.WithoutCancel(ctx)
+
+	err := squ.executeLines(ctx, cnx, input.TGInput.SqlQuery, lines, batchFields)
+	if err != nil {
+		squ.logger.Error().Err(err).Int("start_line", firstLine).Int("end_line", lastLine).Msg("failed to execute batch")
+
+		if !input.TGInput.BisectOnFailure || ctx.Err() != nil {
+			if err := squ.blockRepo.Update(statCtx, block, domain.NewFailureRange(firstLine, lastLine), false); err != nil {
+				squ.logger.Error().Err(err).Msg("failed to update block with failure")
+			}
+			return
+		}
+
+		rejects, untried := squ.bisect(ctx, cnx, input.TGInput.SqlQuery, lines, batchFields, firstLine, err)
+		if len(rejects) > 0 {
+			if err := squ.blockRepo.Reject(statCtx, block, rejects); err != nil {
+				squ.logger.Error().Err(err).Msg("failed to update block with rejected rows")
+			}
+		}
+		if untried > 0 {
+			if err := squ.blockRepo.Update(statCtx, block, domain.NewFailureRange(untried, lastLine), false); err != nil {
+				squ.logger.Error().Err(err).Msg("failed to update block with failure")
+			}
+		}
+		if len(rejects) > 0 || untried > 0 {
+			return
+		}
+	}
+
+	if err := squ.blockRepo.Update(statCtx, block, nil, true); err != nil {
+		squ.logger.Error().Err(err).Msg("failed to update block on success")
+	}
+}
+
+func (squ *SQLBatchQueryUsecase) executeLines(ctx context.Context, cnx *gorm.DB, sqlQuery string, lines [][]string, batchFields []string) error {
 	records, err := csvmapper.MapBatchLines(lines, batchFields)
 	if err != nil {
-		squ.logger.Error().Err(err).Msg("failed to map batch lines")
-		return
+		return err
 	}
 
-	batchSize := input.TGInput.BatchSize
-	start, end := idx*batchSize, min(idx*batchSize+len(lines), len(input.BLInput.Lines))
+	return squ.sqlQueryRepo.ExecuteBatch(ctx, cnx, sqlQuery, records)
+}
+
+// bisect runs the lines of a batch which failed with cause again, in two halves, each in its own transaction.
+// The halves which fail are split again down to single lines, which are returned as rejected with their error.
+// Bisecting stops when the batch is cancelled : the number of the first line left untried is then returned, the
+// untried lines running up to the end of the batch, and 0 once every line was tried.
+func (squ *SQLBatchQueryUsecase) bisect(ctx context.Context, cnx *gorm.DB, sqlQuery string, lines [][]string, batchFields []string, firstLine int, cause error) ([]*domain.RejectedRow, int) {
+	// a part failing with the cancellation is untried, its error isn't the one of its lines
+	if ctx.Err() != nil {
+		return nil, firstLine
+	}
 
-	if execErr := squ.sqlQueryRepo.ExecuteBatch(ctx, input.TGInput.SqlQuery, records); execErr != nil {
-		squ.logger.Error().Err(err).Msg("failed to execute batch")
-		if err := squ.blockRepo.Update(ctx, block, domain.NewFailureRange(start, end), false); err != nil {
-			squ.logger.Error().Err(err).Msg("failed to update block with failure")
+	if len(lines) == 1 {
+		values, err := json.Marshal(lines[0])
+		if err != nil {
+			values = []byte("[]")
 		}
-		return
+		return []*domain.RejectedRow{domain.NewRejectedRow(firstLine, string(values), cause.Error())}, 0
 	}
 
-	if err := squ.blockRepo.Update(ctx, block, nil, true); err != nil {
-		squ.logger.Error().Err(err).Msg("failed to update block on success")
+	half := len(lines) / 2
+	var rejects []*domain.RejectedRow
+	for _, part := range []struct {
+		lines     [][]string
+		firstLine int
+	}{{lines[:half], firstLine}, {lines[half:], firstLine + half}} {
+		if ctx.Err() != nil {
+			return rejects, part.firstLine
+		}
+
+		if err := squ.executeLines(ctx, cnx, sqlQuery, part.lines, batchFields); err != nil {
+			partRejects, untried := squ.bisect(ctx, cnx, sqlQuery, part.lines, batchFields, part.firstLine, err)
+			rejects = append(rejects, partRejects...)
+			if untried > 0 {
+				return rejects, untried
+			}
+		}
+	}
+
+	return rejects, 0
+}
+
+// Rejects returns the rejected rows of a batch, with the batch fields of its target
+func (squ *SQLBatchQueryUsecase) Rejects(ctx context.Context, batchStatId string) (*dto.BatchRejects, error) {
+	batchStat, err := squ.batchStatRepo.FindById(ctx, batchStatId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, batchStatId)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	rejects, err := squ.blockRepo.FindRejectsByBatchStatID(ctx, batchStat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	batchRejects := &dto.BatchRejects{Rows: rejects}
+	if target, exists := squ.config.Get().GetTargetByName(batchStat.TargetName); exists {
+		batchRejects.Fields = strings.Split(target.BatchFields, ";")
+	}
+
+	return batchRejects, nil
 }
@@ -5,78 +5,439 @@ import (
 
 	"github.com/willbrid/api-gateway-sql/config"
 	"github.com/willbrid/api-gateway-sql/internal/dto"
+	"github.com/willbrid/api-gateway-sql/internal/dto/paginator"
 	"github.com/willbrid/api-gateway-sql/internal/pkg/confighelper"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/paramschema"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/resultshape"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlqueryhelper"
+	"github.com/willbrid/api-gateway-sql/internal/pkg/sqlscript"
 	"github.com/willbrid/api-gateway-sql/internal/repository"
 	"github.com/willbrid/api-gateway-sql/pkg/database/external"
 
+	"gorm.io/gorm"
+
 	"context"
 	"errors"
-	"strings"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 )
 
+const functionKind string = "function"
+
 var (
-	errUnknownDatasource error = errors.New("unknown datasource name")
+	errUnknownDatasource  error = errors.New("unknown datasource name")
+	ErrNotStreamable      error = errors.New("target can't be streamed")
+	ErrNotPaginated       error = errors.New("target doesn't return rows by pages")
+	ErrInvalidFilter      error = errors.New("invalid filter or sort")
+	ErrInvalidTransaction error = errors.New("invalid transaction")
 )
 
 type SQLQueryUsecase struct {
-	repo   *repository.SQLQueryRepo
-	config *config.Config
-	logger zerolog.Logger
+	repo        *repository.SQLQueryRepo
+	datasources *external.Registry
+	config      *config.Store
+	logger      zerolog.Logger
 }
 
-func NewSQLQueryUsecase(repo *repository.SQLQueryRepo, config *config.Config, logger zerolog.Logger) *SQLQueryUsecase {
+func NewSQLQueryUsecase(repo *repository.SQLQueryRepo, datasources *external.Registry, config *config.Store, logger zerolog.Logger) *SQLQueryUsecase {
 	return &SQLQueryUsecase{
-		repo:   repo,
-		config: config,
-		logger: logger.With().Str("layer", "usecase").Str("component", "sqlquery").Logger(),
+		repo:        repo,
+		datasources: datasources,
+		config:      config,
+		logger:      logger.With().Str("layer", "usecase").Str("component", "sqlquery").Logger(),
 	}
 }
 
 func (squ *SQLQueryUsecase) ExecuteSingle(ctx context.Context, sqlquery *dto.SQLQueryInput) (*dto.SQLQueryOutput, error) {
-	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config, sqlquery.TargetName)
+	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config.Get(), sqlquery.TargetName)
 	if err != nil {
 		squ.logger.Error().Err(err).Msg("unable to get target and database from config")
 		return nil, err
 	}
 
-	cnx, err := external.NewDatabase(*cfgdb)
+	ctx = withColumnTypes(ctx, target)
+
+	params, err := paramschema.Validate(target.Params, sqlquery.PostParams)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("invalid query params")
+		return nil, err
+	}
+
+	cnx, release, err := squ.datasources.Get(cfgdb.Name)
 	if err != nil {
-		squ.logger.Error().Err(err).Msg("unable to open database connection")
+		squ.logger.Error().Err(err).Msg("unable to get database connection")
 		return nil, err
 	}
+	defer release()
+
+	if len(target.Steps) > 0 {
+		return squ.executeSteps(ctx, cnx, target, sqlquery, params)
+	}
 
-	squ.repo.SetDB(cnx)
-	defer squ.repo.CloseDB()
+	if target.Kind != "" {
+		return squ.executeCall(ctx, cnx, target, sqlquery, params)
+	}
 
-	result, err := squ.repo.Execute(ctx, target.SqlQuery, sqlquery.PostParams)
+	query, params, err := buildQuery(target, cnx.Dialector.Name(), sqlquery, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("unable to build query")
+		return nil, err
+	}
+
+	result, err := squ.repo.Execute(ctx, cnx, query, target.Mode, params)
 	if err != nil {
 		squ.logger.Error().Err(err).Msg("unable to execute single query")
 		return nil, err
 	}
 
+	if target.Shape != nil && result.Rows != nil {
+		if result.Rows, err = resultshape.Nest(result.Rows, target.Shape.Key); err != nil {
+			squ.logger.Error().Err(err).Str("target", target.Name).Msg("unable to shape single query rows")
+			return nil, err
+		}
+		result.AffectedRows = int64(len(result.Rows))
+	}
+
 	squ.logger.Info().Msg("single query executed")
 	return result, nil
 }
 
+// executeSteps runs the statements of a target in a single transaction, the rows and affected rows of the
+// last statement are also the ones of the target
+func (squ *SQLQueryUsecase) executeSteps(ctx context.Context, cnx *gorm.DB, target *config.Target, sqlquery *dto.SQLQueryInput, params map[string]any) (*dto.SQLQueryOutput, error) {
+	if len(sqlquery.Filters) > 0 || len(sqlquery.Sorts) > 0 {
+		squ.logger.Error().Str("target", target.Name).Msg("filters on a multi-statement target")
+		return nil, fmt.Errorf("%w: target runs several statements", ErrInvalidFilter)
+	}
+
+	steps := make([]dto.SQLStep, len(target.Steps))
+	for idx, step := range target.Steps {
+		steps[idx] = dto.SQLStep{Name: step.Name, Query: step.SqlQuery, Mode: step.Mode}
+	}
+
+	outputs, err := squ.repo.ExecuteSteps(ctx, cnx, steps, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("unable to execute steps, transaction rolled back")
+		return nil, err
+	}
+
+	last := outputs[len(outputs)-1]
+	squ.logger.Info().Int("steps", len(outputs)).Msg("steps executed")
+	return &dto.SQLQueryOutput{Rows: last.Rows, AffectedRows: last.AffectedRows, Steps: outputs}, nil
+}
+
+// ExecuteTransaction runs the targets of the input in order in a single transaction of their datasource, a failure
+// rolls back every target and is returned as a *dto.StepError telling which target failed. Only targets
+// running a single sql query can be part of a transaction.
+func (squ *SQLQueryUsecase) ExecuteTransaction(ctx context.Context, input *dto.SQLTransactionInput) (*dto.SQLQueryOutput, error) {
+	if len(input.Steps) == 0 {
+		squ.logger.Error().Msg("transaction without targets")
+		return nil, fmt.Errorf("%w: no target to run", ErrInvalidTransaction)
+	}
+
+	var (
+		datasource string
+		steps      = make([]dto.SQLStep, len(input.Steps))
+	)
+
+	for idx, sqlquery := range input.Steps {
+		target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config.Get(), sqlquery.TargetName)
+		if err != nil {
+			squ.logger.Error().Err(err).Int("step", idx).Msg("unable to get target and database from config")
+			return nil, &dto.StepError{Index: idx, Name: sqlquery.TargetName, Err: fmt.Errorf("%w: %v", ErrInvalidTransaction, err)}
+		}
+
+		stepError := func(err error) error {
+			return &dto.StepError{Index: idx, Name: target.Name, Err: err}
+		}
+
+		switch {
+		case idx == 0:
+			datasource = cfgdb.Name
+		case cfgdb.Name != datasource:
+			squ.logger.Error().Str("target", target.Name).Msg("transaction over several datasources")
+			return nil, stepError(fmt.Errorf("%w: target uses datasource %s, not %s", ErrInvalidTransaction, cfgdb.Name, datasource))
+		}
+
+		if target.Multi || target.Kind != "" || len(target.Steps) > 0 {
+			squ.logger.Error().Str("target", target.Name).Msg("target can't run in a transaction")
+			return nil, stepError(fmt.Errorf("%w: target doesn't run a single sql query", ErrInvalidTransaction))
+		}
+
+		params, err := paramschema.Validate(target.Params, sqlquery.PostParams)
+		if err != nil {
+			squ.logger.Error().Err(err).Str("target", target.Name).Msg("invalid query params")
+			return nil, stepError(err)
+		}
+
+		steps[idx] = dto.SQLStep{Name: target.Name, Query: target.SqlQuery, Mode: target.Mode, Params: params}
+	}
+
+	cnx, release, err := squ.datasources.Get(datasource)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to get database connection")
+		return nil, err
+	}
+	defer release()
+
+	outputs, err := squ.repo.ExecuteSteps(ctx, cnx, steps, nil)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to execute transaction, transaction rolled back")
+		return nil, err
+	}
+
+	last := outputs[len(outputs)-1]
+	squ.logger.Info().Int("steps", len(outputs)).Msg("transaction executed")
+	return &dto.SQLQueryOutput{Rows: last.Rows, AffectedRows: last.AffectedRows, Steps: outputs}, nil
+}
+
+// executeCall calls the procedure of a target, or selects the rows of its function, with the params of the target
+// as arguments in their declaration order
+func (squ *SQLQueryUsecase) executeCall(ctx context.Context, cnx *gorm.DB, target *config.Target, sqlquery *dto.SQLQueryInput, params map[string]any) (*dto.SQLQueryOutput, error) {
+	if len(sqlquery.Filters) > 0 || len(sqlquery.Sorts) > 0 {
+		squ.logger.Error().Str("target", target.Name).Msg("filters on a procedure target")
+		return nil, fmt.Errorf("%w: target calls a %s", ErrInvalidFilter, target.Kind)
+	}
+
+	if target.Kind == functionKind {
+		functionParams := make([]sqlqueryhelper.ProcedureParam, len(target.Params))
+		for idx, param := range target.Params {
+			functionParams[idx] = sqlqueryhelper.ProcedureParam{Name: param.Name, Direction: param.Direction}
+		}
+
+		query, err := sqlqueryhelper.FunctionQuery(cnx.Dialector.Name(), target.Procedure, functionParams)
+		if err != nil {
+			squ.logger.Error().Err(err).Str("target", target.Name).Msg("unable to build function query")
+			return nil, err
+		}
+
+		result, err := squ.repo.Execute(ctx, cnx, query, sqlqueryhelper.QueryMode, params)
+		if err != nil {
+			squ.logger.Error().Err(err).Str("function", target.Procedure).Msg("unable to execute function")
+			return nil, err
+		}
+
+		squ.logger.Info().Str("function", target.Procedure).Msg("function executed")
+		return result, nil
+	}
+
+	procedure := dto.ProcedureCall{Name: target.Procedure, Params: make([]dto.ProcedureParam, len(target.Params))}
+	for idx, param := range target.Params {
+		procedure.Params[idx] = dto.ProcedureParam{Name: param.Name, Type: param.Type, Direction: param.Direction}
+	}
+
+	result, err := squ.repo.CallProcedure(ctx, cnx, procedure, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("procedure", target.Procedure).Msg("unable to call procedure")
+		return nil, err
+	}
+
+	squ.logger.Info().Str("procedure", target.Procedure).Int("result_sets", len(result.ResultSets)).Msg("procedure called")
+	return result, nil
+}
+
+// StreamSingle runs a target returning rows and sends them to the writer as they are read. A paginated target is
+// refused, its rows are only returned by pages of at most max_size rows, and so is a shaped target, whose rows are
+// only nested into documents once they are all read, a multi-statement target or a procedure or function target.
+func (squ *SQLQueryUsecase) StreamSingle(ctx context.Context, sqlquery *dto.SQLQueryInput, writer dto.RowWriter) (int64, error) {
+	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config.Get(), sqlquery.TargetName)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to get target and database from config")
+		return 0, err
+	}
+
+	if target.Pagination != nil {
+		squ.logger.Error().Str("target", target.Name).Msg("paginated target asked in a streamed format")
+		return 0, fmt.Errorf("%w in another format than json", ErrNotPaginated)
+	}
+
+	if target.Shape != nil {
+		squ.logger.Error().Str("target", target.Name).Msg("shaped target asked in a streamed format")
+		return 0, fmt.Errorf("%w: its rows are nested into json documents", ErrNotStreamable)
+	}
+
+	if len(target.Steps) > 0 {
+		squ.logger.Error().Str("target", target.Name).Msg("multi-statement target asked in a streamed format")
+		return 0, fmt.Errorf("%w: it runs several statements", ErrNotStreamable)
+	}
+
+	if target.Kind != "" {
+		squ.logger.Error().Str("target", target.Name).Msg("procedure or function target asked in a streamed format")
+		return 0, fmt.Errorf("%w: it calls a %s", ErrNotStreamable, target.Kind)
+	}
+
+	ctx = withColumnTypes(ctx, target)
+
+	params, err := paramschema.Validate(target.Params, sqlquery.PostParams)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("invalid query params")
+		return 0, err
+	}
+
+	cnx, release, err := squ.datasources.Get(cfgdb.Name)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to get database connection")
+		return 0, err
+	}
+	defer release()
+
+	query, params, err := buildQuery(target, cnx.Dialector.Name(), sqlquery, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("unable to build query")
+		return 0, err
+	}
+
+	if !sqlqueryhelper.IsQueryMode(target.Mode, cnx.Dialector.Name(), query) {
+		squ.logger.Error().Str("target", target.Name).Msg(ErrNotStreamable.Error())
+		return 0, fmt.Errorf("%w: it doesn't return rows", ErrNotStreamable)
+	}
+
+	count, err := squ.repo.Stream(ctx, cnx, query, params, writer)
+	if err != nil {
+		squ.logger.Error().Err(err).Int64("rows", count).Msg("unable to stream single query")
+		return count, err
+	}
+
+	squ.logger.Info().Int64("rows", count).Msg("single query streamed")
+	return count, nil
+}
+
+// ExecutePage runs a paginated target and returns the rows of the requested page. Numbered pages come with the
+// total count of rows, keyset pages come with the cursor of the next page while rows remain.
+func (squ *SQLQueryUsecase) ExecutePage(ctx context.Context, sqlquery *dto.SQLQueryInput, pageRequest *paginator.PageRequest) (*paginator.PageResponse, error) {
+	target, cfgdb, err := confighelper.GetTargetAndDatabase(squ.config.Get(), sqlquery.TargetName)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to get target and database from config")
+		return nil, err
+	}
+
+	ctx = withColumnTypes(ctx, target)
+
+	params, err := paramschema.Validate(target.Params, sqlquery.PostParams)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("invalid query params")
+		return nil, err
+	}
+
+	if target.Pagination != nil && target.Pagination.CursorColumn != "" && len(sqlquery.Sorts) > 0 {
+		squ.logger.Error().Str("target", target.Name).Msg("sort on a keyset paginated target")
+		return nil, fmt.Errorf("%w: keyset pages are sorted by their cursor column %q", ErrInvalidFilter, target.Pagination.CursorColumn)
+	}
+
+	cnx, release, err := squ.datasources.Get(cfgdb.Name)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to get database connection")
+		return nil, err
+	}
+	defer release()
+
+	dialect := cnx.Dialector.Name()
+	query, params, err := buildQuery(target, dialect, sqlquery, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Str("target", target.Name).Msg("unable to build query")
+		return nil, err
+	}
+
+	if target.Pagination == nil || !sqlqueryhelper.IsQueryMode(target.Mode, dialect, query) {
+		squ.logger.Error().Str("target", target.Name).Msg(ErrNotPaginated.Error())
+		return nil, ErrNotPaginated
+	}
+
+	countResult, err := squ.repo.Execute(ctx, cnx, sqlqueryhelper.CountQuery(dialect, query), sqlqueryhelper.QueryMode, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to execute count query")
+		return nil, err
+	}
+
+	total, err := countValue(countResult)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to read count query result")
+		return nil, err
+	}
+
+	if target.Pagination.CursorColumn != "" {
+		return squ.executeKeysetPage(ctx, cnx, target, query, params, total, pageRequest)
+	}
+
+	page := sqlqueryhelper.Page{Limit: pageRequest.Limit(), Offset: pageRequest.Offset()}
+	result, err := squ.repo.Execute(ctx, cnx, sqlqueryhelper.PageQuery(dialect, query, page), sqlqueryhelper.QueryMode, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to execute page query")
+		return nil, err
+	}
+
+	squ.logger.Info().Int("page", pageRequest.PageNum).Msg("page query executed")
+	return paginator.NewPageResponse(rowsAny(result.Rows), total, pageRequest), nil
+}
+
+// executeKeysetPage fetches the rows after the cursor, one row more than the page size tells a next page exists
+func (squ *SQLQueryUsecase) executeKeysetPage(ctx context.Context, cnx *gorm.DB, target *config.Target, query string, params map[string]any, total int64, pageRequest *paginator.PageRequest) (*paginator.PageResponse, error) {
+	pagination := target.Pagination
+	page := sqlqueryhelper.Page{
+		Limit:        pageRequest.Limit() + 1,
+		CursorColumn: pagination.CursorColumn,
+		Descending:   pagination.CursorOrder == "desc",
+	}
+
+	if pageRequest.Cursor != "" {
+		cursor, err := paginator.DecodeCursor(pageRequest.Cursor)
+		if err != nil {
+			squ.logger.Error().Err(err).Str("target", target.Name).Msg("invalid page cursor")
+			return nil, err
+		}
+		cursorParams := make(map[string]any, len(params)+1)
+		maps.Copy(cursorParams, params)
+		cursorParams[sqlqueryhelper.CursorParam] = cursor
+		params = cursorParams
+		page.AfterCursor = true
+	}
+
+	result, err := squ.repo.Execute(ctx, cnx, sqlqueryhelper.PageQuery(cnx.Dialector.Name(), query, page), sqlqueryhelper.QueryMode, params)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to execute keyset page query")
+		return nil, err
+	}
+
+	rows := result.Rows
+	nextCursor := ""
+	if len(rows) > pageRequest.Limit() {
+		rows = rows[:pageRequest.Limit()]
+		if nextCursor, err = paginator.EncodeCursor(rows[len(rows)-1][pagination.CursorColumn]); err != nil {
+			squ.logger.Error().Err(err).Msg("unable to encode next page cursor")
+			return nil, err
+		}
+	}
+
+	pageResponse := paginator.NewPageResponse(rowsAny(rows), total, pageRequest)
+	pageResponse.NextCursor = nextCursor
+
+	squ.logger.Info().Bool("last", nextCursor == "").Msg("keyset page query executed")
+	return pageResponse, nil
+}
+
 func (squ *SQLQueryUsecase) ExecuteInit(ctx context.Context, sqlinit *dto.SQLInitDatabaseInput) error {
-	database, exist := squ.config.GetDatabaseByDataSourceName(sqlinit.Datasource)
+	database, exist := squ.config.Get().GetDatabaseByDataSourceName(sqlinit.Datasource)
 	if !exist {
 		squ.logger.Error().Msg(errUnknownDatasource.Error())
 		return errUnknownDatasource
 	}
 
-	cnx, err := external.NewDatabase(database)
+	cnx, release, err := squ.datasources.Get(database.Name)
 	if err != nil {
-		squ.logger.Error().Err(err).Msg("unable to open database connection")
+		squ.logger.Error().Err(err).Msg("unable to get database connection")
 		return err
 	}
+	defer release()
 
-	squ.repo.SetDB(cnx)
-	defer squ.repo.CloseDB()
-
-	queries := strings.Split(sqlinit.SQLFileContent, ";")
+	queries, err := sqlscript.Split(cnx.Dialector.Name(), sqlinit.SQLFileContent)
+	if err != nil {
+		squ.logger.Error().Err(err).Msg("unable to split init script")
+		return err
+	}
 
-	if err := squ.repo.ExecuteInit(ctx, queries); err != nil {
+	if err := squ.repo.ExecuteInit(ctx, cnx, queries); err != nil {
 		squ.logger.Error().Err(err).Msg("unable to execute init query")
 		return err
 	}
@@ -84,3 +445,88 @@ func (squ *SQLQueryUsecase) ExecuteInit(ctx context.Context, sqlinit *dto.SQLIni
 	squ.logger.Info().Msg("init query executed")
 	return nil
 }
+
+// withColumnTypes returns a context converting the columns of the target column_types to their type
+func withColumnTypes(ctx context.Context, target *config.Target) context.Context {
+	if len(target.ColumnTypes) == 0 {
+		return ctx
+	}
+
+	columnTypes := make(map[string]string, len(target.ColumnTypes))
+	for _, columnType := range target.ColumnTypes {
+		columnTypes[columnType.Column] = columnType.Type
+	}
+
+	return repository.WithColumnTypes(ctx, columnTypes)
+}
+
+func rowsAny(rows []map[string]any) []any {
+	data := make([]any, len(rows))
+	for idx, row := range rows {
+		data[idx] = row
+	}
+
+	return data
+}
+
+// countValue reads the total column of a count query, drivers return it as an integer or as its text
+func countValue(result *dto.SQLQueryOutput) (int64, error) {
+	if len(result.Rows) != 1 {
+		return 0, errors.New("count query didn't return a single row")
+	}
+
+	switch total := result.Rows[0]["total"].(type) {
+	case int64:
+		return total, nil
+	case int32:
+		return int64(total), nil
+	default:
+		return strconv.ParseInt(fmt.Sprint(total), 10, 64)
+	}
+}
+
+// buildQuery renders the conditional sections of the target query with the params, then restricts it to the rows
+// matching the filters of the request, sorted by its sorts. Only the filterable and sortable columns of the target
+// are accepted, the filter values are bound as params.
+func buildQuery(target *config.Target, dialect string, sqlquery *dto.SQLQueryInput, params map[string]any) (string, map[string]any, error) {
+	query, err := sqlqueryhelper.RenderTemplate(target.SqlQuery, params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(sqlquery.Filters) == 0 && len(sqlquery.Sorts) == 0 {
+		return query, params, nil
+	}
+
+	if !sqlqueryhelper.IsQueryMode(target.Mode, dialect, query) {
+		return "", nil, fmt.Errorf("%w: target doesn't return rows", ErrInvalidFilter)
+	}
+
+	filterParams := make(map[string]any, len(params)+len(sqlquery.Filters))
+	maps.Copy(filterParams, params)
+
+	conditions := make([]sqlqueryhelper.Condition, len(sqlquery.Filters))
+	for idx, filter := range sqlquery.Filters {
+		if !slices.Contains(target.Filterable, filter.Column) {
+			return "", nil, fmt.Errorf("%w: column %q can't be filtered", ErrInvalidFilter, filter.Column)
+		}
+		if _, exists := sqlqueryhelper.FilterOperators[filter.Operator]; !exists {
+			return "", nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, filter.Operator)
+		}
+
+		param := sqlqueryhelper.FilterParamPrefix + strconv.Itoa(idx)
+		filterParams[param] = filter.Value
+		conditions[idx] = sqlqueryhelper.Condition{Column: filter.Column, Operator: filter.Operator, Param: param}
+	}
+
+	orders := make([]sqlqueryhelper.Order, len(sqlquery.Sorts))
+	for idx, sort := range sqlquery.Sorts {
+		if !slices.Contains(target.Sortable, sort.Column) {
+			return "", nil, fmt.Errorf("%w: column %q can't be sorted", ErrInvalidFilter, sort.Column)
+		}
+
+		orders[idx] = sqlqueryhelper.Order{Column: sort.Column, Descending: sort.Descending}
+	}
+
+	return sqlqueryhelper.FilterQuery(dialect, query, conditions, orders), filterParams, nil
+}
@@ -8,24 +8,35 @@ import (
 	"github.com/willbrid/api-gateway-sql/internal/dto"
 	"github.com/willbrid/api-gateway-sql/internal/dto/paginator"
 	"github.com/willbrid/api-gateway-sql/internal/repository"
+	"github.com/willbrid/api-gateway-sql/pkg/database/external"
+	"github.com/willbrid/api-gateway-sql/pkg/staging"
 
 	"context"
+	"time"
 )
 
 type ISQLQueryUsecase interface {
 	ExecuteSingle(ctx context.Context, sqlquery *dto.SQLQueryInput) (*dto.SQLQueryOutput, error)
+	StreamSingle(ctx context.Context, sqlquery *dto.SQLQueryInput, writer dto.RowWriter) (int64, error)
+	ExecutePage(ctx context.Context, sqlquery *dto.SQLQueryInput, pageRequest *paginator.PageRequest) (*paginator.PageResponse, error)
+	ExecuteTransaction(ctx context.Context, input *dto.SQLTransactionInput) (*dto.SQLQueryOutput, error)
 	ExecuteInit(ctx context.Context, sqlinit *dto.SQLInitDatabaseInput) error
 }
 
 type ISQLBatchQueryUsecase interface {
-	ExecuteBatch(ctx context.Context, sqlbatchquery *dto.SQLBatchQueryInput) error
+	PrepareBatch(ctx context.Context, sqlbatchquery *dto.SQLBatchQueryInput) (*dto.BatchRun, error)
+	ExecuteBatch(ctx context.Context, run *dto.BatchRun) error
+	PrepareRetry(ctx context.Context, input *dto.SQLBatchRetryInput) (*dto.BatchRetry, error)
+	ExecuteRetry(ctx context.Context, retry *dto.BatchRetry) error
+	CancelBatch(ctx context.Context, batchStatId string) error
+	Rejects(ctx context.Context, batchStatId string) (*dto.BatchRejects, error)
 }
 
 type IBatchStatUsecase interface {
 	ListBatchStats(ctx context.Context, pageRequest *paginator.PageRequest) (*paginator.PageResponse, error)
 	GetBatchStatById(ctx context.Context, uid string) (*domain.BatchStat, error)
 	MarkCompletedBatchStat(ctx context.Context, uid string) error
-	IsAllBatchStatClosed(ctx context.Context) (bool, error)
+	FailStaleBatches(ctx context.Context, staleAfter time.Duration) (int64, error)
 }
 
 type IBlockUsecase interface {
@@ -33,35 +44,48 @@ type IBlockUsecase interface {
 	GetBlockById(ctx context.Context, uid string) (*domain.Block, error)
 }
 
+type IMigrationUsecase interface {
+	Status(ctx context.Context, input *dto.MigrationInput) ([]dto.MigrationStatus, error)
+	Up(ctx context.Context, input *dto.MigrationInput) ([]dto.MigrationStatus, error)
+	Down(ctx context.Context, input *dto.MigrationInput) ([]dto.MigrationStatus, error)
+}
+
 type Usecases struct {
 	ISQLQueryUsecase      ISQLQueryUsecase
 	ISQLBatchQueryUsecase ISQLBatchQueryUsecase
 	IBatchStatUsecase     IBatchStatUsecase
 	IBlockUsecase         IBlockUsecase
+	IMigrationUsecase     IMigrationUsecase
 }
 
 type Deps struct {
-	Repos  *repository.Repositories
-	Config *config.Config
-	Logger zerolog.Logger
+	Repos       *repository.Repositories
+	Datasources *external.Registry
+	Staging     *staging.Store
+	Config      *config.Store
+	Logger      zerolog.Logger
 }
 
 func NewUsecases(deps Deps) *Usecases {
-	sqlQueryUsecase := NewSQLQueryUsecase(deps.Repos.ISQLQueryRepo.(*repository.SQLQueryRepo), deps.Config, deps.Logger)
+	sqlQueryUsecase := NewSQLQueryUsecase(deps.Repos.ISQLQueryRepo.(*repository.SQLQueryRepo), deps.Datasources, deps.Config, deps.Logger)
 	sqlBatchQueryUsecase := NewSQLBatchQueryUsecase(
 		deps.Repos.ISQLQueryRepo.(*repository.SQLQueryRepo),
 		deps.Repos.IBatchStat.(*repository.BatchStatRepo),
 		deps.Repos.IBlock.(*repository.BlockRepo),
+		deps.Datasources,
+		deps.Staging,
 		deps.Config,
 		deps.Logger,
 	)
-	batchStatUsecase := NewBatchStatUsecase(deps.Repos.IBatchStat.(*repository.BatchStatRepo), deps.Logger)
+	batchStatUsecase := NewBatchStatUsecase(deps.Repos.IBatchStat.(*repository.BatchStatRepo), deps.Staging, deps.Logger)
 	blockUsecase := NewBlockUsecase(deps.Repos.IBlock.(*repository.BlockRepo), deps.Logger)
+	migrationUsecase := NewMigrationUsecase(deps.Repos.IMigration.(*repository.MigrationRepo), deps.Datasources, deps.Config, deps.Logger)
 
 	return &Usecases{
 		ISQLQueryUsecase:      sqlQueryUsecase,
 		ISQLBatchQueryUsecase: sqlBatchQueryUsecase,
 		IBatchStatUsecase:     batchStatUsecase,
 		IBlockUsecase:         blockUsecase,
+		IMigrationUsecase:     migrationUsecase,
 	}
 }
@@ -0,0 +1,102 @@
+package csvstream
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+type Block struct {
+	StartLine int
+	EndLine   int
+	Lines     [][]string
+}
+
+// ReadCSVInBlock reads the records of a csv file written in format, by blocks of blockSize records. The lines
+// of the blocks are numbered from 1, the header excluded. Reading stops when ctx is cancelled, with the error
+// of ctx.
+func ReadCSVInBlock(ctx context.Context, file io.Reader, blockSize int, format Format) (chan *Block, chan error) {
+	blockChannel := make(chan *Block)
+	errorChannel := make(chan error, 1)
+
+	go func() {
+		defer close(blockChannel)
+		defer close(errorChannel)
+
+		format = format.withDefaults()
+		reader, err := newRecordReader(file, format)
+		if err != nil {
+			errorChannel <- err
+			return
+		}
+
+		var indexes []int
+		if format.Header {
+			header, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errorChannel <- fmt.Errorf("failed to read the header - error: %w", err)
+				return
+			}
+			if indexes, err = columnIndexes(header, format.Columns); err != nil {
+				errorChannel <- err
+				return
+			}
+		}
+
+		numLine := 0
+		for {
+			block := &Block{
+				StartLine: blockSize*numLine + 1,
+				Lines:     make([][]string, 0, blockSize),
+			}
+
+			for i := range blockSize {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					errorChannel <- fmt.Errorf("failed to read a line %v - start of the block: %v - error: %w", i, block.StartLine, err)
+					return
+				}
+				block.Lines = append(block.Lines, selectColumns(record, indexes))
+			}
+
+			if len(block.Lines) == 0 {
+				break
+			}
+
+			block.EndLine = block.StartLine + len(block.Lines) - 1
+			select {
+			case blockChannel <- block:
+			case <-ctx.Done():
+				errorChannel <- ctx.Err()
+				return
+			}
+			numLine++
+		}
+	}()
+
+	return blockChannel, errorChannel
+}
+
+// selectColumns returns the fields of a record at the indexes of the columns, or the record itself without
+// indexes. A record missing columns becomes an empty line, which fails the mapping of its batch.
+func selectColumns(record []string, indexes []int) []string {
+	if indexes == nil {
+		return record
+	}
+
+	line := make([]string, len(indexes))
+	for idx, position := range indexes {
+		if position >= len(record) {
+			return []string{}
+		}
+		line[idx] = record[position]
+	}
+
+	return line
+}
@@ -0,0 +1,154 @@
+package csvstream_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/pkg/csvstream"
+
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(file io.Reader, blockSize int, format csvstream.Format) ([]*csvstream.Block, error) {
+	blockCh, errCh := csvstream.ReadCSVInBlock(context.Background(), file, blockSize, format)
+
+	var blocks []*csvstream.Block
+	for block := range blockCh {
+		blocks = append(blocks, block)
+	}
+
+	return blocks, <-errCh
+}
+
+func readLines(t *testing.T, file io.Reader, format csvstream.Format) [][]string {
+	t.Helper()
+
+	blocks, err := readAll(file, 2, format)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var lines [][]string
+	for _, block := range blocks {
+		lines = append(lines, block.Lines...)
+	}
+
+	return lines
+}
+
+func TestReadCSVInBlock_Blocks(t *testing.T) {
+	t.Parallel()
+
+	blocks, err := readAll(strings.NewReader("a;1\nb;2\n\nc;3\n"), 2, csvstream.DefaultFormat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	if blocks[0].StartLine != 1 || blocks[0].EndLine != 2 || blocks[1].StartLine != 3 || blocks[1].EndLine != 3 {
+		t.Errorf("got blocks %d-%d and %d-%d, want 1-2 and 3-3", blocks[0].StartLine, blocks[0].EndLine, blocks[1].StartLine, blocks[1].EndLine)
+	}
+	if !reflect.DeepEqual(blocks[1].Lines, [][]string{{"c", "3"}}) {
+		t.Errorf("got lines %q, want [[c 3]]", blocks[1].Lines)
+	}
+}
+
+func TestReadCSVInBlock_QuotedFields(t *testing.T) {
+	t.Parallel()
+
+	file := "\"north; east\";\"say \"\"hi\"\"\"\r\n\"two\r\nlines\";\r\n"
+	expected := [][]string{{"north; east", `say "hi"`}, {"two\nlines", ""}}
+
+	if lines := readLines(t, strings.NewReader(file), csvstream.DefaultFormat); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("got %q, want %q", lines, expected)
+	}
+}
+
+func TestReadCSVInBlock_Format(t *testing.T) {
+	t.Parallel()
+
+	format := csvstream.Format{Delimiter: ',', Quote: '\'', Escape: '\\'}
+	file := `'it\'s','a\\b',plain` + "\n"
+	expected := [][]string{{"it's", `a\b`, "plain"}}
+
+	if lines := readLines(t, strings.NewReader(file), format); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("got %q, want %q", lines, expected)
+	}
+}
+
+func TestReadCSVInBlock_Header(t *testing.T) {
+	t.Parallel()
+
+	format := csvstream.Format{Delimiter: ',', Header: true, Columns: []string{"name", "School Address"}}
+	file := "id, school address ,NAME\n1,Willow Ave,north\n2,Oak St\n"
+	expected := [][]string{{"north", "Willow Ave"}, {}}
+
+	if lines := readLines(t, strings.NewReader(file), format); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("got %q, want %q", lines, expected)
+	}
+
+	format.Columns = []string{"name", "city"}
+	if _, err := readAll(strings.NewReader(file), 2, format); !errors.Is(err, csvstream.ErrInvalidCSV) {
+		t.Errorf("got error %v, want ErrInvalidCSV for a column missing from the header", err)
+	}
+}
+
+func TestReadCSVInBlock_Encodings(t *testing.T) {
+	t.Parallel()
+
+	expected := [][]string{{"école", "Zoë"}}
+
+	utf8BOM := append([]byte{0xef, 0xbb, 0xbf}, "école;Zoë\n"...)
+	if lines := readLines(t, bytes.NewReader(utf8BOM), csvstream.DefaultFormat); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("utf-8: got %q, want %q", lines, expected)
+	}
+
+	latin1 := csvstream.DefaultFormat
+	latin1.Encoding = csvstream.Latin1
+	if lines := readLines(t, bytes.NewReader([]byte("\xe9cole;Zo\xeb\n")), latin1); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("latin-1: got %q, want %q", lines, expected)
+	}
+
+	utf16 := csvstream.DefaultFormat
+	utf16.Encoding = csvstream.UTF16
+	littleEndian := []byte{0xff, 0xfe}
+	for _, char := range "école;Zoë\n" {
+		littleEndian = append(littleEndian, byte(char), byte(char>>8))
+	}
+	if lines := readLines(t, bytes.NewReader(littleEndian), utf16); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("utf-16: got %q, want %q", lines, expected)
+	}
+}
+
+func TestReadCSVInBlock_RejectsInvalidFiles(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"a;\"open\nb;c\n", "a;\"closed\"x;b\n"} {
+		if _, err := readAll(strings.NewReader(file), 2, csvstream.DefaultFormat); !errors.Is(err, csvstream.ErrInvalidCSV) {
+			t.Errorf("got error %v for %q, want ErrInvalidCSV", err, file)
+		}
+	}
+}
+
+func TestReadCSVInBlock_StopsWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blockCh, errCh := csvstream.ReadCSVInBlock(ctx, strings.NewReader("a;1\nb;2\nc;3\n"), 1, csvstream.DefaultFormat)
+
+	if block := <-blockCh; block == nil || block.StartLine != 1 {
+		t.Fatalf("got block %+v, want the first block", block)
+	}
+	cancel()
+
+	for range blockCh {
+	}
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
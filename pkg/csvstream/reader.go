@@ -1,57 +1,262 @@
 package csvstream
 
 import (
-	"encoding/csv"
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encodings of the csv files
+const (
+	UTF8   string = "utf-8"
+	Latin1 string = "latin-1"
+	UTF16  string = "utf-16"
 )
 
-type Block struct {
-	StartLine int
-	EndLine   int
-	Lines     [][]string
+var ErrInvalidCSV error = errors.New("invalid csv file")
+
+// Format describes how a csv file is written
+type Format struct {
+	// Delimiter separates the fields of a record, ';' by default
+	Delimiter rune
+	// Quote encloses the fields holding delimiters, quotes or line breaks, '"' by default
+	Quote rune
+	// Escape makes the next quote of a quoted field a literal quote. When it is the quote itself, which is the
+	// default, quotes are escaped by doubling them as in RFC 4180.
+	Escape rune
+	// Encoding of the file: utf-8 (default), latin-1 or utf-16. A byte order mark is removed, and tells the
+	// byte order of an utf-16 file.
+	Encoding string
+	// Header tells that the first record holds the names of the columns. The lines are then made of the values
+	// of Columns, in their order, whatever the order of the columns in the file.
+	Header  bool
+	Columns []string
+}
+
+// DefaultFormat reads semicolon separated utf-8 files without header
+var DefaultFormat = Format{Delimiter: ';', Quote: '"', Escape: '"', Encoding: UTF8}
+
+func (f Format) withDefaults() Format {
+	if f.Delimiter == 0 {
+		f.Delimiter = DefaultFormat.Delimiter
+	}
+	if f.Quote == 0 {
+		f.Quote = DefaultFormat.Quote
+	}
+	if f.Escape == 0 {
+		f.Escape = f.Quote
+	}
+	if f.Encoding == "" {
+		f.Encoding = DefaultFormat.Encoding
+	}
+
+	return f
+}
+
+// decode returns a reader of the utf-8 text of the file
+func decode(file io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(encoding) {
+	case UTF8:
+		return transform.NewReader(file, unicode.BOMOverride(unicode.UTF8.NewDecoder())), nil
+	case Latin1:
+		return transform.NewReader(file, charmap.ISO8859_1.NewDecoder()), nil
+	case UTF16:
+		return transform.NewReader(file, unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder()), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported encoding %s", ErrInvalidCSV, encoding)
+	}
+}
+
+// recordReader reads the records of a csv file as described by RFC 4180, with the delimiter, the quote and the
+// escape character of its format. Quoted fields can hold line breaks, and empty lines are skipped.
+type recordReader struct {
+	reader *bufio.Reader
+	format Format
+	// line is the number of the file line being read, a record can span several lines
+	line int
+}
+
+func newRecordReader(file io.Reader, format Format) (*recordReader, error) {
+	decoded, err := decode(file, format.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordReader{reader: bufio.NewReader(decoded), format: format, line: 1}, nil
+}
+
+// Read returns the fields of the next record, or io.EOF at the end of the file
+func (r *recordReader) Read() ([]string, error) {
+	for {
+		next, _, err := r.reader.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+
+		switch next {
+		case '\n':
+			r.line++
+		case '\r':
+		default:
+			if err := r.reader.UnreadRune(); err != nil {
+				return nil, err
+			}
+			return r.readRecord()
+		}
+	}
+}
+
+func (r *recordReader) readRecord() ([]string, error) {
+	var record []string
+	for {
+		field, last, err := r.readField()
+		if err != nil {
+			return nil, err
+		}
+		record = append(record, field)
+		if last {
+			return record, nil
+		}
+	}
 }
 
-func ReadCSVInBlock(file multipart.File, blockSize int) (chan *Block, chan error) {
-	blockChannel := make(chan *Block)
-	errorChannel := make(chan error)
+// readField returns a field and whether it ends the record
+func (r *recordReader) readField() (string, bool, error) {
+	next, _, err := r.reader.ReadRune()
+	if err == io.EOF {
+		return "", true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if next == r.format.Quote {
+		return r.readQuotedField()
+	}
 
-	go func() {
-		numLine := 0
-		reader := csv.NewReader(file)
+	var field strings.Builder
+	for {
+		switch next {
+		case r.format.Delimiter:
+			return field.String(), false, nil
+		case '\n':
+			r.line++
+			return strings.TrimSuffix(field.String(), "\r"), true, nil
+		default:
+			field.WriteRune(next)
+		}
 
-		defer close(blockChannel)
-		defer close(errorChannel)
+		next, _, err = r.reader.ReadRune()
+		if err == io.EOF {
+			return strings.TrimSuffix(field.String(), "\r"), true, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+	}
+}
+
+func (r *recordReader) readQuotedField() (string, bool, error) {
+	startLine := r.line
+	quote, escape := r.format.Quote, r.format.Escape
+
+	var field strings.Builder
+	for {
+		next, _, err := r.reader.ReadRune()
+		if err == io.EOF {
+			return "", false, fmt.Errorf("%w: line %d: quoted field is not closed", ErrInvalidCSV, startLine)
+		}
+		if err != nil {
+			return "", false, err
+		}
 
-		for {
-			block := &Block{
-				StartLine: blockSize*numLine + 1,
-				Lines:     make([][]string, 0, blockSize),
+		switch {
+		case next == escape && escape != quote:
+			escaped, _, err := r.reader.ReadRune()
+			if err != nil {
+				return "", false, fmt.Errorf("%w: line %d: quoted field is not closed", ErrInvalidCSV, startLine)
+			}
+			if escaped != quote && escaped != escape {
+				field.WriteRune(next)
+			}
+			r.countLine(escaped)
+			field.WriteRune(escaped)
+		case next == quote:
+			following, _, err := r.reader.ReadRune()
+			if err == io.EOF {
+				return field.String(), true, nil
+			}
+			if err != nil {
+				return "", false, err
 			}
 
-			for i := range blockSize {
-				record, err := reader.Read()
-				if err == io.EOF {
-					break
+			switch following {
+			case quote:
+				if escape != quote {
+					return "", false, fmt.Errorf("%w: line %d: unescaped quote in a quoted field", ErrInvalidCSV, r.line)
 				}
-				if err != nil {
-					errorChannel <- fmt.Errorf("failed to read a line %v - start of the block: %v - error: %w", i, block.StartLine, err)
-					return
+				field.WriteRune(quote)
+			case r.format.Delimiter:
+				return field.String(), false, nil
+			case '\n':
+				r.line++
+				return field.String(), true, nil
+			case '\r':
+				if end, _, err := r.reader.ReadRune(); err != nil || end == '\n' {
+					r.line++
+					return field.String(), true, nil
 				}
-				block.Lines = append(block.Lines, strings.Split(record[0], ";"))
+				return "", false, fmt.Errorf("%w: line %d: unexpected character after a quoted field", ErrInvalidCSV, r.line)
+			default:
+				return "", false, fmt.Errorf("%w: line %d: unexpected character after a quoted field", ErrInvalidCSV, r.line)
 			}
-
-			if len(block.Lines) == 0 {
-				break
+		case next == '\r':
+			// line breaks of quoted fields are kept as \n, as encoding/csv does
+			if following, _, err := r.reader.ReadRune(); err == nil && following != '\n' {
+				if err := r.reader.UnreadRune(); err != nil {
+					return "", false, err
+				}
 			}
+			r.line++
+			field.WriteRune('\n')
+		default:
+			r.countLine(next)
+			field.WriteRune(next)
+		}
+	}
+}
+
+func (r *recordReader) countLine(char rune) {
+	if char == '\n' {
+		r.line++
+	}
+}
+
+// columnIndexes returns the position in the header of each column, matched regardless of case and surrounding
+// spaces
+func columnIndexes(header []string, columns []string) ([]int, error) {
+	positions := make(map[string]int, len(header))
+	for idx, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, exists := positions[name]; exists {
+			return nil, fmt.Errorf("%w: header column %s is duplicated", ErrInvalidCSV, name)
+		}
+		positions[name] = idx
+	}
 
-			block.EndLine = block.StartLine + len(block.Lines) - 1
-			blockChannel <- block
-			numLine++
+	indexes := make([]int, len(columns))
+	for idx, column := range columns {
+		position, exists := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !exists {
+			return nil, fmt.Errorf("%w: header has no column %s", ErrInvalidCSV, column)
 		}
-	}()
+		indexes[idx] = position
+	}
 
-	return blockChannel, errorChannel
+	return indexes, nil
 }
@@ -0,0 +1,206 @@
+package external
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+
+	"errors"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownDatasource error = errors.New("unknown datasource name")
+)
+
+// Registry keeps one pooled connection per configured datasource.
+// It is safe for concurrent use.
+type Registry struct {
+	mu        sync.Mutex
+	databases map[string]config.Database
+	pools     map[string]*pool
+}
+
+// pool is the connection of a datasource with the number of its users. A pool retired by a reload is closed once
+// its last user releases it.
+type pool struct {
+	cnx     *gorm.DB
+	users   int
+	retired bool
+}
+
+func NewRegistry(databases []config.Database) *Registry {
+	registry := &Registry{
+		databases: make(map[string]config.Database, len(databases)),
+		pools:     make(map[string]*pool, len(databases)),
+	}
+
+	for _, database := range databases {
+		registry.databases[database.Name] = database
+	}
+
+	return registry
+}
+
+// Get returns the pooled connection of a datasource, opening it on first use, and the function releasing it. The
+// connection stays open until it is released, even when a reload modifies or removes its datasource.
+// The connection is opened without holding the registry lock, so that a slow datasource doesn't block the others :
+// when requests open the same datasource at the same time, the first pool registered is kept and the others closed.
+func (r *Registry) Get(name string) (*gorm.DB, func(), error) {
+	for {
+		r.mu.Lock()
+		if p, exist := r.pools[name]; exist {
+			p.users++
+			r.mu.Unlock()
+			return p.cnx, r.releaseFunc(p), nil
+		}
+		database, exist := r.databases[name]
+		r.mu.Unlock()
+
+		if !exist {
+			return nil, nil, errUnknownDatasource
+		}
+
+		cnx, err := openPool(database)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		r.mu.Lock()
+		p, registered := r.pools[name]
+		if !registered && r.databases[name] == database {
+			p = &pool{cnx: cnx, users: 1}
+			r.pools[name] = p
+			r.mu.Unlock()
+			return p.cnx, r.releaseFunc(p), nil
+		}
+		if registered {
+			p.users++
+		}
+		r.mu.Unlock()
+
+		_ = closePool(cnx)
+		if registered {
+			return p.cnx, r.releaseFunc(p), nil
+		}
+		// a reload modified or removed the datasource while it was opened
+	}
+}
+
+// releaseFunc returns the function ending a use of a pool, which can be called several times
+func (r *Registry) releaseFunc(p *pool) func() {
+	var once sync.Once
+	return func() { once.Do(func() { r.release(p) }) }
+}
+
+// release ends a use of a pool, and closes the pool when it is retired and has no more users
+func (r *Registry) release(p *pool) {
+	r.mu.Lock()
+	p.users--
+	idle := p.retired && p.users == 0
+	r.mu.Unlock()
+
+	if idle {
+		_ = closePool(p.cnx)
+	}
+}
+
+// Reload replaces the datasource definitions. The pools of removed or modified datasources are retired : the next
+// requests open a new pool, and a retired pool is closed once the requests, streams and batches using it end.
+func (r *Registry) Reload(databases []config.Database) error {
+	r.mu.Lock()
+
+	next := make(map[string]config.Database, len(databases))
+	for _, database := range databases {
+		next[database.Name] = database
+	}
+
+	idle := make(map[string]*gorm.DB)
+	for name, p := range r.pools {
+		if database, exist := next[name]; exist && database == r.databases[name] {
+			continue
+		}
+
+		p.retired = true
+		if p.users == 0 {
+			idle[name] = p.cnx
+		}
+		delete(r.pools, name)
+	}
+
+	r.databases = next
+	r.mu.Unlock()
+
+	var errs []error
+	for name, cnx := range idle {
+		if err := closePool(cnx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close datasource %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes every opened pool
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for name, p := range r.pools {
+		if err := closePool(p.cnx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close datasource %s: %w", name, err))
+		}
+		delete(r.pools, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// openPool connects to a datasource with its pool settings
+func openPool(database config.Database) (*gorm.DB, error) {
+	cnx, err := NewDatabase(database)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := configurePool(cnx, database); err != nil {
+		_ = closePool(cnx)
+		return nil, err
+	}
+
+	return cnx, nil
+}
+
+// configurePool applies the datasource pool settings, zero values keep the driver defaults
+func configurePool(cnx *gorm.DB, database config.Database) error {
+	sqlDB, err := cnx.DB()
+	if err != nil {
+		return err
+	}
+
+	if database.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(database.MaxOpenConns)
+	}
+	if database.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(database.MaxIdleConns)
+	}
+	if database.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(database.ConnMaxLifetime)
+	}
+	if database.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(database.ConnMaxIdleTime)
+	}
+
+	return nil
+}
+
+func closePool(cnx *gorm.DB) error {
+	sqlDB, err := cnx.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
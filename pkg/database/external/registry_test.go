@@ -0,0 +1,161 @@
+package external_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/config"
+	"github.com/willbrid/api-gateway-sql/pkg/database/external"
+
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry_ReloadKeepsPoolsInUse(t *testing.T) {
+	t.Parallel()
+
+	database := config.Database{Name: "school", Type: "sqlite", Dbname: filepath.Join(t.TempDir(), "school"), Timeout: time.Second}
+	registry := external.NewRegistry([]config.Database{database})
+	t.Cleanup(func() { _ = registry.Close() })
+
+	cnx, release, err := registry.Get("school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	database.MaxOpenConns = 4
+	if err := registry.Reload([]config.Database{database}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cnx.Exec("select 1").Error; err != nil {
+		t.Fatalf("got error %v on a pool in use after a reload, want it still open", err)
+	}
+
+	next, releaseNext, err := registry.Get("school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer releaseNext()
+	if next == cnx {
+		t.Errorf("got the retired pool after a reload, want a new one")
+	}
+
+	release()
+	release()
+	if err := cnx.Exec("select 1").Error; err == nil {
+		t.Errorf("got a retired pool still open after its last release")
+	}
+	if err := next.Exec("select 1").Error; err != nil {
+		t.Errorf("got error %v on the new pool, want it open", err)
+	}
+}
+
+func TestRegistry_ReloadClosesIdlePools(t *testing.T) {
+	t.Parallel()
+
+	database := config.Database{Name: "school", Type: "sqlite", Dbname: filepath.Join(t.TempDir(), "school"), Timeout: time.Second}
+	registry := external.NewRegistry([]config.Database{database})
+	t.Cleanup(func() { _ = registry.Close() })
+
+	cnx, release, err := registry.Get("school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+
+	if err := registry.Reload(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cnx.Exec("select 1").Error; err == nil {
+		t.Errorf("got an idle pool of a removed datasource still open")
+	}
+	if _, _, err := registry.Get("school"); err == nil {
+		t.Errorf("got a pool of a removed datasource")
+	}
+}
+
+func TestRegistry_SlowDatasourceDoesntBlockOthers(t *testing.T) {
+	t.Parallel()
+
+	// the listener accepts connections but never answers, as an unreachable database
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			if _, err := listener.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+
+	registry := external.NewRegistry([]config.Database{
+		{Name: "slow", Type: "postgres", Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port, Username: "x", Password: "x", Dbname: "x", Timeout: 2 * time.Second},
+		{Name: "school", Type: "sqlite", Dbname: filepath.Join(t.TempDir(), "school"), Timeout: time.Second},
+	})
+	t.Cleanup(func() { _ = registry.Close() })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, _, err := registry.Get("slow"); err == nil {
+			t.Errorf("got a pool of an unreachable datasource")
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	_, release, err := registry.Get("school")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("got a datasource opened in %v while another one was dialing, want it not blocked", elapsed)
+	}
+
+	wg.Wait()
+}
+
+func TestRegistry_ConcurrentGetsShareAPool(t *testing.T) {
+	t.Parallel()
+
+	database := config.Database{Name: "school", Type: "sqlite", Dbname: filepath.Join(t.TempDir(), "school"), Timeout: time.Second}
+	registry := external.NewRegistry([]config.Database{database})
+	t.Cleanup(func() { _ = registry.Close() })
+
+	const users = 10
+	var wg sync.WaitGroup
+	pools := make(chan any, users)
+	for range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cnx, release, err := registry.Get("school")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			defer release()
+			if err := cnx.Exec("select 1").Error; err != nil {
+				t.Errorf("got error %v on a shared pool, want it open", err)
+			}
+			pools <- cnx
+		}()
+	}
+	wg.Wait()
+	close(pools)
+
+	var first any
+	for cnx := range pools {
+		if first == nil {
+			first = cnx
+		} else if cnx != first {
+			t.Errorf("got several pools for a datasource, want a single one")
+		}
+	}
+}
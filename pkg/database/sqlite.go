@@ -12,7 +12,8 @@ type SqliteAppDatabase struct {
 }
 
 func NewSqliteAppDatabase(sqlitedb string) (*SqliteAppDatabase, error) {
-	dsn := fmt.Sprintf("/data/%s.db", sqlitedb)
+	// the batches write their statistics concurrently: writers wait for the lock, and readers don't block them
+	dsn := fmt.Sprintf("/data/%s.db?_busy_timeout=5000&_journal_mode=WAL", sqlitedb)
 
 	cnx, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 
@@ -0,0 +1,81 @@
+package jobmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// Manager keeps the jobs running in the process, by id, so that they can be cancelled
+type Manager struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+type job struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+func NewManager() *Manager {
+	return &Manager{jobs: map[string]*job{}}
+}
+
+// Register adds a job about to run, which can be cancelled from now on
+func (m *Manager) Register(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.jobs[id]; !exists {
+		m.jobs[id] = &job{}
+	}
+}
+
+// Run returns the context of a job, derived from parent and cancelled with the job, and the function to call
+// once the job ends. The context of a job cancelled before it runs is already cancelled.
+func (m *Manager) Run(parent context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, exists := m.jobs[id]
+	if !exists {
+		j = &job{}
+		m.jobs[id] = j
+	}
+	j.cancel = cancel
+	if j.cancelled {
+		cancel()
+	}
+
+	return ctx, func() {
+		m.Remove(id)
+		cancel()
+	}
+}
+
+// Cancel cancels a job and reports whether it was registered
+func (m *Manager) Cancel(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, exists := m.jobs[id]
+	if !exists {
+		return false
+	}
+
+	j.cancelled = true
+	if j.cancel != nil {
+		j.cancel()
+	}
+
+	return true
+}
+
+// Remove drops a job, when it ends or won't run
+func (m *Manager) Remove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.jobs, id)
+}
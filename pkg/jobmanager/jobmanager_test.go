@@ -0,0 +1,54 @@
+package jobmanager_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/pkg/jobmanager"
+
+	"context"
+	"testing"
+)
+
+func TestManager_Cancel(t *testing.T) {
+	t.Parallel()
+
+	manager := jobmanager.NewManager()
+	if manager.Cancel("batch-1") {
+		t.Errorf("cancelled a job which isn't registered")
+	}
+
+	ctx, done := manager.Run(context.Background(), "batch-1")
+	if ctx.Err() != nil {
+		t.Fatalf("got a cancelled context before the job is cancelled")
+	}
+	if !manager.Cancel("batch-1") {
+		t.Fatalf("failed to cancel a running job")
+	}
+	if ctx.Err() == nil {
+		t.Errorf("got a context still running after the job is cancelled")
+	}
+
+	done()
+	if manager.Cancel("batch-1") {
+		t.Errorf("cancelled a job which has ended")
+	}
+}
+
+func TestManager_CancelBeforeRun(t *testing.T) {
+	t.Parallel()
+
+	manager := jobmanager.NewManager()
+	manager.Register("batch-1")
+	if !manager.Cancel("batch-1") {
+		t.Fatalf("failed to cancel a registered job")
+	}
+
+	ctx, done := manager.Run(context.Background(), "batch-1")
+	defer done()
+	if ctx.Err() == nil {
+		t.Errorf("got a context still running for a job cancelled before it runs")
+	}
+
+	manager.Remove("batch-1")
+	if manager.Cancel("batch-1") {
+		t.Errorf("cancelled a removed job")
+	}
+}
@@ -0,0 +1,58 @@
+package staging_test
+
+import (
+	"github.com/willbrid/api-gateway-sql/pkg/staging"
+
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	store, err := staging.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := store.Save("batch-1", strings.NewReader("a;b\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := store.Open("batch-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, _ := io.ReadAll(file)
+	_ = file.Close()
+	if string(content) != "a;b\n" {
+		t.Errorf("got %q, want %q", content, "a;b\n")
+	}
+
+	if err := store.Remove("batch-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Open("batch-1"); !errors.Is(err, staging.ErrNotFound) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+	if err := store.Remove("batch-1"); err != nil {
+		t.Errorf("got error %v removing a missing file, want none", err)
+	}
+}
+
+func TestStore_RejectsPaths(t *testing.T) {
+	t.Parallel()
+
+	store, err := staging.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, id := range []string{"", "..", "../batch", "dir/batch"} {
+		if err := store.Save(id, strings.NewReader("")); err == nil {
+			t.Errorf("saved a file under %q, want an error", id)
+		}
+	}
+}